@@ -0,0 +1,556 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/infosecual/nosy/src/triage"
+	nt "github.com/infosecual/nosy/src/types"
+)
+
+// exit codes returned by the nosy cli
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// errUsage is returned by a subcommand when it was invoked incorrectly
+var errUsage = errors.New("invalid usage")
+
+// subcommand describes a single nosy action, e.g. "nosy fuzz"
+type subcommand struct {
+	name    string
+	args    string
+	summary string
+	// flags registers the subcommand's flags on fs and returns the function
+	// that runs the subcommand once the flags have been parsed
+	flags func(fs *flag.FlagSet) func(ctx context.Context, args []string) error
+}
+
+var subcommands = []subcommand{
+	{
+		name:    "init",
+		args:    "<target>.yaml",
+		summary: "download and initialize a target environment",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			skip_build := fs.Bool("skip-build", false, "do not rebuild the nosy-neighbor image")
+			runner := runner_flag(fs)
+			return func(ctx context.Context, args []string) error {
+				if err := load_init_config_and_runner(args, *runner); err != nil {
+					return err
+				}
+				return init_target(ctx, !*skip_build)
+			}
+		},
+	},
+	{
+		name:    "update",
+		args:    "<target>.yaml",
+		summary: "fetch a newer commit of the target, keeping its corpora and crashes",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			runner := runner_flag(fs)
+			return func(ctx context.Context, args []string) error {
+				if err := load_online_config_and_runner(args, *runner); err != nil {
+					return err
+				}
+				return update_target(ctx)
+			}
+		},
+	},
+	{
+		name:    "generate",
+		args:    "<target>.yaml",
+		summary: "generate fuzz harnesses for the target",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			runner := runner_flag(fs)
+			return func(ctx context.Context, args []string) error {
+				if err := load_config_and_runner(args, *runner); err != nil {
+					return err
+				}
+				return generate_fuzz_harnesses(ctx)
+			}
+		},
+	},
+	{
+		name:    "fuzz",
+		args:    "<target>.yaml",
+		summary: "build the fuzzers and fuzz the target",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			seconds := fs.Int("seconds", 0, "override seconds_per_target_function from the target config")
+			runner := runner_flag(fs)
+			opts := fuzz_flags(fs)
+			return func(ctx context.Context, args []string) error {
+				if err := load_config_and_runner(args, *runner); err != nil {
+					return err
+				}
+				if *seconds > 0 {
+					TargetConfig.TestTimeSeconds = *seconds
+				}
+				return fuzz(ctx, *opts)
+			}
+		},
+	},
+	{
+		name:    "run",
+		args:    "<target>.yaml",
+		summary: "init, generate and fuzz the target, stopping at the first failure",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			skip_build := fs.Bool("skip-build", false, "do not rebuild the nosy-neighbor image")
+			seconds := fs.Int("seconds", 0, "override seconds_per_target_function from the target config")
+			runner := runner_flag(fs)
+			opts := fuzz_flags(fs)
+			return func(ctx context.Context, args []string) error {
+				if err := load_init_config_and_runner(args, *runner); err != nil {
+					return err
+				}
+				if *seconds > 0 {
+					TargetConfig.TestTimeSeconds = *seconds
+				}
+				return run_pipeline(ctx, !*skip_build, *opts)
+			}
+		},
+	},
+	{
+		name:    "status",
+		args:    "<target>.yaml",
+		summary: "show how far the target has progressed",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			watch := fs.Bool("watch", false, "show a live dashboard of the running campaign until interrupted")
+			interval := fs.Duration("interval", 2*time.Second, "how often the dashboard is refreshed")
+			return func(ctx context.Context, args []string) error {
+				if err := load_target_config(args); err != nil {
+					return err
+				}
+				if *watch {
+					if *interval <= 0 {
+						return fmt.Errorf("%w: -interval must be positive", errUsage)
+					}
+					return watch_status(ctx, *interval)
+				}
+				return status()
+			}
+		},
+	},
+	{
+		name:    "repro",
+		args:    "<target>.yaml <crash-id|file>",
+		summary: "replay a saved crash input and print its stack trace",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			runner := runner_flag(fs)
+			return func(ctx context.Context, args []string) error {
+				if len(args) != 2 {
+					return fmt.Errorf("%w: expected a target YAML file and a crash input ID or file", errUsage)
+				}
+				if err := load_config_and_runner(args[:1], *runner); err != nil {
+					return err
+				}
+				return repro(ctx, args[1])
+			}
+		},
+	},
+	{
+		name:    "minimize",
+		args:    "<target>.yaml [<bucket-id>...]",
+		summary: "shrink the saved input of each crash bucket",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			runner := runner_flag(fs)
+			opts := minimize_options{}
+			fs.IntVar(&opts.batch, "batch", 64, "number of candidate inputs tried per run")
+			fs.IntVar(&opts.rounds, "rounds", 100, "maximum number of runs per crash")
+			return func(ctx context.Context, args []string) error {
+				if len(args) == 0 {
+					return fmt.Errorf("%w: expected exactly one target YAML file", errUsage)
+				}
+				if opts.batch < 1 || opts.rounds < 1 {
+					return fmt.Errorf("%w: -batch and -rounds must be at least 1", errUsage)
+				}
+				if err := load_config_and_runner(args[:1], *runner); err != nil {
+					return err
+				}
+				return minimize_crashes(ctx, args[1:], opts)
+			}
+		},
+	},
+	{
+		name:    "regress",
+		args:    "<target>.yaml [<bucket-id>...]",
+		summary: "write a standalone regression test for each crash bucket",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			runner := runner_flag(fs)
+			return func(ctx context.Context, args []string) error {
+				if len(args) == 0 {
+					return fmt.Errorf("%w: expected exactly one target YAML file", errUsage)
+				}
+				if err := load_config_and_runner(args[:1], *runner); err != nil {
+					return err
+				}
+				return regress(ctx, args[1:])
+			}
+		},
+	},
+	{
+		name:    "coverage",
+		args:    "<target>.yaml",
+		summary: "replay the fuzzing corpus with coverage and summarize what it reached",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			runner := runner_flag(fs)
+			return func(ctx context.Context, args []string) error {
+				if err := load_config_and_runner(args, *runner); err != nil {
+					return err
+				}
+				return coverage_report(ctx)
+			}
+		},
+	},
+	{
+		name:    "config",
+		args:    "validate <target>.yaml | init <repo-url|path>",
+		summary: "check a target config, or write one for a repository",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			output := fs.String("o", "", "init: file to write the config to (default: <repo>.yaml)")
+			force := fs.Bool("force", false, "init: overwrite an existing config")
+			return func(ctx context.Context, args []string) error {
+				if len(args) != 2 {
+					return fmt.Errorf("%w: expected validate and a target YAML file, or init and a repository", errUsage)
+				}
+				switch args[0] {
+				case "validate":
+					return validate_config(args[1])
+				case "init":
+					return init_config(ctx, args[1], *output, *force)
+				}
+				return fmt.Errorf("%w: unknown config command %q", errUsage, args[0])
+			}
+		},
+	},
+	{
+		name:    "triage",
+		args:    "<log> [<log>...]",
+		summary: "extract crashes from go test -fuzz logs into CSV and JSON reports",
+		flags: func(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+			csv_path := fs.String("csv", "", "write a CSV report to this file (default: CSV to stdout)")
+			json_path := fs.String("json", "", "write a JSON report, including stack traces, to this file")
+			buckets_path := fs.String("buckets", "", "file the crash buckets are kept in across runs (default: not kept)")
+			frames := fs.Int("frames", triage.DefaultFrames, "number of stack frames that identify a crash bucket, an existing -buckets file keeps its own")
+			return func(ctx context.Context, args []string) error {
+				if len(args) == 0 {
+					return fmt.Errorf("%w: expected at least one log file", errUsage)
+				}
+				if *frames <= 0 {
+					return fmt.Errorf("%w: -frames must be at least 1", errUsage)
+				}
+				return triage_files(args, *csv_path, *json_path, *buckets_path, *frames)
+			}
+		},
+	},
+}
+
+// legacy action flags from before nosy had subcommands
+var legacy_actions = map[string]string{
+	"--init":             "init",
+	"--generate-harness": "generate",
+	"--fuzz":             "fuzz",
+}
+
+// default help menu
+func print_help_menu() {
+	fmt.Println("Usage: nosy <command> [flags] <target>.yaml")
+	fmt.Println("")
+	fmt.Println("Commands:")
+	for _, c := range subcommands {
+		fmt.Printf("\t%-10s%s\n", c.name, c.summary)
+	}
+	fmt.Println("")
+	fmt.Println("Run \"nosy <command> --help\" for the flags of a command.")
+	fmt.Println("")
+	fmt.Println("Example usage:")
+	fmt.Println("\t# This will download the target repo")
+	fmt.Println("\tgo run . init example_source.yaml")
+	fmt.Println("")
+	fmt.Println("\t# This will parse the target source and generate")
+	fmt.Println("\t# the fuzz harnesses")
+	fmt.Println("\tgo run . generate example_source.yaml")
+	fmt.Println("")
+	fmt.Println("\t# This will build the fuzzers and begin fuzzing the target")
+	fmt.Println("\t# in a docker container")
+	fmt.Println("\tgo run . fuzz example_source.yaml")
+	fmt.Println("")
+	fmt.Println("\t# This will do all of the above in one go")
+	fmt.Println("\tgo run . run example_source.yaml")
+	fmt.Println("")
+}
+
+// load_config parses the target YAML file given as the only positional
+// argument of a subcommand into the global config, warning about unknown
+// keys and failing on invalid fields
+func load_config(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: expected exactly one target YAML file", errUsage)
+	}
+	if !strings.HasSuffix(args[0], ".yaml") {
+		return fmt.Errorf("%w: the target config must be a YAML file (.yaml)", errUsage)
+	}
+	config, check, err := check_config(args[0])
+	if err != nil {
+		return err
+	}
+	for _, warning := range check.Warnings {
+		fmt.Fprintf(os.Stderr, "nosy: warning: %s: %s\n", args[0], warning)
+	}
+	if err := check.Err(args[0]); err != nil {
+		return err
+	}
+	TargetConfig = config
+	ConfigPath = args[0]
+	return nil
+}
+
+// load_target_config loads the target config and completes it with what
+// nosy init found out about the target
+func load_target_config(args []string) error {
+	if err := load_config(args); err != nil {
+		return err
+	}
+	return resolve_target_module()
+}
+
+// resolve_target_module fills in the import prefix and module declaration
+// from the module nosy init found in the target's go.mod. Before init the
+// import prefix is only known if the config sets it.
+func resolve_target_module() error {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
+	module, err := nt.LoadTargetModule(filepath.Join(target_dir, nt.TargetModuleFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		if TargetConfig.TargetModuleDeclaration == "" {
+			TargetConfig.TargetModuleDeclaration = TargetConfig.TargetRepoImportPrefix
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, warning := range module.Resolve(&TargetConfig) {
+		fmt.Fprintf(os.Stderr, "nosy: warning: %s: %s\n", ConfigPath, warning)
+	}
+	TargetCommit = module.Commit
+	return nil
+}
+
+// require_import_prefix fails commands that work on an initialized target
+// when neither the config nor nosy init gave its import prefix
+func require_import_prefix() error {
+	if TargetConfig.TargetRepoImportPrefix == "" {
+		return fmt.Errorf("target %s has not been initialized, run \"nosy init\" first or set target_repo_import_prefix", TargetConfig.TargetRepo)
+	}
+	return nil
+}
+
+// check_config loads and checks a target config, including the runner,
+// which only nosy itself knows
+func check_config(path string) (nt.TargetRepoConfig, nt.ConfigCheck, error) {
+	config, check, err := nt.CheckTargetConfig(path)
+	if err != nil {
+		return config, check, err
+	}
+	if config.Runner != "" {
+		if _, ok := runners[config.Runner]; !ok {
+			check.Errors = append(check.Errors, nt.ConfigIssue{Field: "runner",
+				Message: fmt.Sprintf("unknown runner %q, expected one of: %s", config.Runner, strings.Join(runner_names(), ", "))})
+		}
+	}
+	return config, check, nil
+}
+
+// validate_config is "nosy config validate", it lists every problem with a
+// target config and the defaults filled in
+func validate_config(path string) error {
+	_, check, err := check_config(path)
+	if err != nil {
+		return err
+	}
+	for _, issue := range check.Errors {
+		fmt.Printf("error:   %s\n", issue)
+	}
+	for _, issue := range check.Warnings {
+		fmt.Printf("warning: %s\n", issue)
+	}
+	for _, issue := range check.Defaults {
+		fmt.Printf("default: %s\n", issue)
+	}
+	if len(check.Errors) > 0 {
+		return fmt.Errorf("%s: %d invalid fields", path, len(check.Errors))
+	}
+	fmt.Printf("%s is valid\n", path)
+	return nil
+}
+
+// runner_flag registers the flag selecting the execution backend
+func runner_flag(fs *flag.FlagSet) *string {
+	return fs.String("runner", "", fmt.Sprintf("execution backend, one of: %s (default: the config's runner or docker)",
+		strings.Join(runner_names(), ", ")))
+}
+
+// load_config_and_runner loads the target config of an initialized target
+// and selects the execution backend, preferring the one given on the
+// command line
+func load_config_and_runner(args []string, runner string) error {
+	if err := load_online_config_and_runner(args, runner); err != nil {
+		return err
+	}
+	return use_module_proxy()
+}
+
+// load_online_config_and_runner is load_config_and_runner for the commands
+// that fetch modules, which keep the network even for offline targets
+func load_online_config_and_runner(args []string, runner string) error {
+	if err := load_target_config(args); err != nil {
+		return err
+	}
+	if err := require_import_prefix(); err != nil {
+		return err
+	}
+	return select_runner_flag(runner)
+}
+
+// load_init_config_and_runner is load_config_and_runner for the commands
+// that initialize the target, which find out about the target themselves
+func load_init_config_and_runner(args []string, runner string) error {
+	if err := load_config(args); err != nil {
+		return err
+	}
+	return select_runner_flag(runner)
+}
+
+// select_runner_flag selects the execution backend, preferring the one
+// given on the command line
+func select_runner_flag(runner string) error {
+	r, err := select_runner(runner)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	ActiveRunner = r
+	return nil
+}
+
+// fuzz_flags registers the flags controlling the fuzz stage
+func fuzz_flags(fs *flag.FlagSet) *fuzz_options {
+	opts := &fuzz_options{}
+	fs.IntVar(&opts.jobs, "jobs", 1, "number of harnesses to fuzz concurrently")
+	fs.Float64Var(&opts.cpus, "cpus", 0, "CPU quota of each fuzzing job (default: the host's cores split between jobs)")
+	fs.BoolVar(&opts.resume, "resume", false, "resume the previous campaign, skipping harnesses that already finished")
+	fs.IntVar(&opts.budget, "budget", 0, "total fuzzing seconds, shared out to the harnesses still finding new inputs (default: fuzz_budget_seconds from the target config)")
+	fs.StringVar(&opts.metrics_addr, "metrics-addr", "", "serve Prometheus metrics of the campaign on this address, e.g. :9100 (default: off)")
+	return opts
+}
+
+// run_pipeline chains init, generate and fuzz, stopping at the first stage
+// that fails
+func run_pipeline(ctx context.Context, build_image bool, opts fuzz_options) error {
+	stages := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{"init", func(ctx context.Context) error { return init_target(ctx, build_image) }},
+		{"generate", generate_fuzz_harnesses},
+		{"fuzz", func(ctx context.Context) error { return fuzz(ctx, opts) }},
+	}
+	for _, stage := range stages {
+		fmt.Printf("\n==> nosy %s\n", stage.name)
+		if err := stage.run(ctx); err != nil {
+			return fmt.Errorf("stage %s failed: %w", stage.name, err)
+		}
+	}
+	return nil
+}
+
+// run_subcommand parses the flags for c and runs it, returning the process
+// exit code
+func run_subcommand(c subcommand, args []string) int {
+	fs := flag.NewFlagSet("nosy "+c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: nosy %s [flags] %s\n\n%s\n", c.name, c.args, c.summary)
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	run := c.flags(fs)
+	// flags may also follow the arguments, as in nosy fuzz x.yaml -jobs 4,
+	// parsing stops at each argument and picks up after it. "-h" and
+	// "--help" are handled by the flag package.
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return exitOK
+			}
+			return exitUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		// everything after "--" is an argument
+		if len(args) > fs.NArg() && args[len(args)-fs.NArg()-1] == "--" {
+			positional = append(positional, fs.Args()...)
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	// Ctrl-C stops the running stage, which kills any running containers
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := run(ctx, positional)
+	print_step_summary()
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(os.Stderr, "nosy %s: %v\n\n", c.name, err)
+		fs.Usage()
+		return exitUsage
+	default:
+		fmt.Fprintf(os.Stderr, "nosy %s: error: %v\n", c.name, err)
+		return exitFailure
+	}
+}
+
+func main() {
+	// print help menu if invalid arguments
+	if len(os.Args) < 2 {
+		print_help_menu()
+		os.Exit(exitUsage)
+	}
+
+	name := os.Args[1]
+	if name == "-h" || name == "--help" || name == "help" {
+		print_help_menu()
+		os.Exit(exitOK)
+	}
+	if legacy, ok := legacy_actions[name]; ok {
+		name = legacy
+	}
+
+	for _, c := range subcommands {
+		if c.name == name {
+			os.Exit(run_subcommand(c, os.Args[2:]))
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "unknown command:", os.Args[1])
+	print_help_menu()
+	os.Exit(exitUsage)
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	nt "github.com/infosecual/nosy/src/types"
+)
+
+// directories whose packages are rarely worth fuzzing, and why
+var suggested_ignores = map[string]string{
+	"mock":        "mocks",
+	"mocks":       "mocks",
+	"fake":        "test doubles",
+	"fakes":       "test doubles",
+	"testutil":    "test helpers",
+	"testutils":   "test helpers",
+	"testhelpers": "test helpers",
+	"testing":     "test helpers",
+	"e2e":         "end to end tests",
+	"cmd":         "main packages",
+	"examples":    "examples",
+	"example":     "examples",
+	"tools":       "build tooling",
+}
+
+// the packages listed as comments in a new config, past this they are counted
+const max_listed_packages = 50
+
+// the header go marks generated files with
+var generated_header = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// repo_package is a package found while inspecting a repository
+type repo_package struct {
+	// dir is the package's directory relative to the repository root
+	dir       string
+	main      bool
+	generated bool
+}
+
+// repo_module is a module nested below the repository root
+type repo_module struct {
+	// dir is the module's directory relative to the repository root
+	dir  string
+	path string
+}
+
+// inspected_repo is what nosy config init found out about a repository
+type inspected_repo struct {
+	url    string
+	local  string
+	branch string
+	// commit is the commit checked out when the repository was inspected
+	commit   string
+	module   *nt.TargetModule
+	packages []repo_package
+	// modules are the go.mod files found below the root, the import path of
+	// a package is that of the nearest one
+	modules []repo_module
+}
+
+// inspect_package reads the package clause and generated header of the go
+// files of dir, reporting if it holds a package and whether it is a main
+// package and every file in it is generated
+func inspect_package(dir string) (repo_package, bool) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	pkg := repo_package{generated: true}
+	found := false
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		found = true
+		generated := false
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if generated_header.MatchString(line) {
+				generated = true
+			}
+			if strings.HasPrefix(line, "package ") {
+				pkg.main = pkg.main || strings.TrimSpace(strings.TrimPrefix(line, "package ")) == "main"
+				break
+			}
+		}
+		f.Close()
+		pkg.generated = pkg.generated && generated
+	}
+	return pkg, found
+}
+
+// find_packages lists the packages and nested modules of the repository at
+// root, leaving out vendored, testdata and hidden directories
+func find_packages(root string) ([]repo_package, []repo_module, error) {
+	var packages []repo_package
+	var modules []repo_module
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil && rel != "." {
+			// a go.mod without a module line is not a module, go ignores
+			// it too
+			if m, err := nt.InferTargetModule(path); err == nil {
+				modules = append(modules, repo_module{dir: rel, path: m.ModulePath})
+			}
+		}
+		pkg, ok := inspect_package(path)
+		if !ok {
+			return nil
+		}
+		pkg.dir = rel
+		packages = append(packages, pkg)
+		return nil
+	})
+	return packages, modules, err
+}
+
+// import_path returns the import path of the package in dir, from the
+// nearest module containing it
+func (r *inspected_repo) import_path(dir string) string {
+	prefix, rel := r.module.ImportPrefix, dir
+	best := ""
+	for _, m := range r.modules {
+		if (dir == m.dir || strings.HasPrefix(dir, m.dir+"/")) && len(m.dir) > len(best) {
+			best = m.dir
+			prefix, rel = m.path, strings.TrimPrefix(strings.TrimPrefix(dir, m.dir), "/")
+		}
+	}
+	if rel == "." || rel == "" {
+		return prefix
+	}
+	return prefix + "/" + rel
+}
+
+// nested_modules returns the modules below the root that are not part of
+// the module or workspace fuzzed, generate does not reach their packages
+func (r *inspected_repo) nested_modules() []repo_module {
+	var nested []repo_module
+	for _, m := range r.modules {
+		member := false
+		for _, path := range r.module.Modules {
+			if path == m.path {
+				member = true
+				break
+			}
+		}
+		if !member {
+			nested = append(nested, m)
+		}
+	}
+	return nested
+}
+
+// suggest_ignores returns ignore_packages entries for the directories of
+// mocks, test helpers, main packages and generated code, with the reason
+// for each. Each entry is a regexp matching the directory's package and the
+// packages below it, which are not listed again.
+func (r *inspected_repo) suggest_ignores() ([]string, map[string]string) {
+	reasons := map[string]string{}
+	for _, pkg := range r.packages {
+		parts := strings.Split(pkg.dir, "/")
+		for i, part := range parts {
+			if reason, ok := suggested_ignores[part]; ok {
+				reasons[strings.Join(parts[:i+1], "/")] = reason
+				break
+			}
+		}
+		// ignoring the root would ignore the whole repository
+		if pkg.dir == "." {
+			continue
+		}
+		if pkg.generated {
+			reasons[pkg.dir] = "generated code"
+		} else if pkg.main {
+			reasons[pkg.dir] = "main package"
+		}
+	}
+	var dirs []string
+	for dir := range reasons {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	var entries []string
+	entry_reasons := map[string]string{}
+	for _, dir := range dirs {
+		covered := false
+		for _, parent := range dirs {
+			if parent != dir && strings.HasPrefix(dir, parent+"/") {
+				covered = true
+				break
+			}
+		}
+		if covered {
+			continue
+		}
+		// plain entries match any import path containing them, anchor the
+		// entry so .../cmd does not also ignore .../cmdutil
+		entry := "re:" + regexp.QuoteMeta(r.import_path(dir)) + "(/.*)?"
+		entries = append(entries, entry)
+		entry_reasons[entry] = reasons[dir]
+	}
+	return entries, entry_reasons
+}
+
+// git_output runs git with args and returns its trimmed output
+func git_output(args ...string) (string, error) {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok && len(exit.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exit.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// inspect_repo looks at a local checkout, or a shallow clone of a URL, of
+// the repository to configure
+func inspect_repo(ctx context.Context, source string) (*inspected_repo, error) {
+	repo := &inspected_repo{url: source}
+	dir := source
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return nil, err
+		}
+		// the working tree is fuzzed as it is, the remote is only noted
+		repo.local, dir = abs, abs
+		repo.url, _ = git_output("-C", dir, "remote", "get-url", "origin")
+		if branch, err := git_output("-C", dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+			repo.branch = branch
+		}
+	} else {
+		tmp, err := os.MkdirTemp("", "nosy-config-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		dir = filepath.Join(tmp, "repo")
+		fmt.Printf("Cloning %s to inspect it...\n", source)
+		clone := exec.CommandContext(ctx, "git", "clone", "--depth", "1", "--quiet", source, dir)
+		clone.Stderr = os.Stderr
+		if err := clone.Run(); err != nil {
+			return nil, fmt.Errorf("git clone %s: %w", source, err)
+		}
+		// a fresh clone has the remote's default branch checked out
+		if branch, err := git_output("-C", dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+			repo.branch = branch
+		}
+	}
+	repo.commit, _ = git_output("-C", dir, "rev-parse", "HEAD")
+
+	module, err := nt.InferTargetModule(dir)
+	if err != nil {
+		return nil, err
+	}
+	repo.module = module
+	if repo.packages, repo.modules, err = find_packages(dir); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// target_name returns the target_repo_name for a repository URL or path
+func target_name(source string) string {
+	name := strings.TrimSuffix(filepath.Base(strings.TrimRight(source, "/")), ".git")
+	name = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' {
+			return '-'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		return "target"
+	}
+	return name
+}
+
+// render_config writes a target config for repo, with the inspection's
+// findings as comments so the result is ready to edit
+func (r *inspected_repo) render_config(name string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "---\n")
+	fmt.Fprintf(&b, "# generated by nosy config init, see example_source.yaml for every option\n")
+	fmt.Fprintf(&b, "target_repo_name: %s\n", name)
+	fmt.Fprintf(&b, "%s\n", strings.TrimSpace("target_repo_url: "+r.url))
+	if r.local != "" {
+		fmt.Fprintf(&b, "# the working tree is copied as it is, uncommitted changes included,\n")
+		fmt.Fprintf(&b, "# remove it to clone target_repo_url instead\n")
+		fmt.Fprintf(&b, "target_local_path: %s\n", r.local)
+	}
+	if r.branch != "" {
+		fmt.Fprintf(&b, "target_repo_branch: %s\n", r.branch)
+	} else {
+		fmt.Fprintf(&b, "# no branch checked out, the default branch is cloned\n")
+		fmt.Fprintf(&b, "target_repo_branch:\n")
+	}
+	if r.commit != "" {
+		fmt.Fprintf(&b, "# pin the commit or tag fuzzed for a reproducible campaign, e.g. the\n")
+		fmt.Fprintf(&b, "# one inspected: %s\n", r.commit)
+	}
+	fmt.Fprintf(&b, "target_repo_commit:\n")
+	fmt.Fprintf(&b, "\n# read from the repository's go.mod, nosy init checks them again\n")
+	fmt.Fprintf(&b, "target_repo_import_prefix: %s\n", r.module.ImportPrefix)
+	fmt.Fprintf(&b, "target_mod_self_declaration: %s\n", r.module.ModulePath)
+	if nested := r.nested_modules(); len(nested) > 0 {
+		fmt.Fprintf(&b, "# packages of these nested modules are not part of %s and are\n", r.module.ModulePath)
+		fmt.Fprintf(&b, "# not fuzzed, a config with a module's directory as target_local_path\n")
+		fmt.Fprintf(&b, "# fuzzes it on its own:\n")
+		for _, m := range nested {
+			fmt.Fprintf(&b, "#  - %s (%s)\n", m.path, m.dir)
+		}
+	}
+	fmt.Fprintf(&b, "\n")
+	if r.module.GoVersion != "" {
+		fmt.Fprintf(&b, "# the target requires go %s\n", r.module.GoVersion)
+	}
+	fmt.Fprintf(&b, "go_version: %s\n", nt.DefaultGoVersion)
+	fmt.Fprintf(&b, "runner: docker\n")
+	fmt.Fprintf(&b, "\nharness_gen_deps:\n")
+	fmt.Fprintf(&b, "  - go get golang.org/x/tools\n")
+	fmt.Fprintf(&b, "  - go get golang.org/x/tools/internal/imports\n")
+	fmt.Fprintf(&b, "  - go get golang.org/x/tools/internal/gocommand\n")
+	fmt.Fprintf(&b, "  - go get gopkg.in/yaml.v2\n")
+
+	entries, reasons := r.suggest_ignores()
+	fmt.Fprintf(&b, "\n# suggested from the directory names and generated code found\n")
+	fmt.Fprintf(&b, "ignore_packages:\n")
+	for _, entry := range entries {
+		// single quoted so YAML keeps the regexp's backslashes
+		fmt.Fprintf(&b, "  - '%s' # %s\n", entry, reasons[entry])
+	}
+	fmt.Fprintf(&b, "ignore_functions:\n")
+	fmt.Fprintf(&b, "ignore_types:\n")
+	fmt.Fprintf(&b, "substitute_packages:\n")
+
+	fmt.Fprintf(&b, "\n# to fuzz only some of the %d packages found, list them here:\n", len(r.packages))
+	for i, pkg := range r.packages {
+		if i == max_listed_packages {
+			fmt.Fprintf(&b, "#  ... and %d more\n", len(r.packages)-i)
+			break
+		}
+		fmt.Fprintf(&b, "#  - %s\n", r.import_path(pkg.dir))
+	}
+	fmt.Fprintf(&b, "include_packages:\n")
+	fmt.Fprintf(&b, "include_functions:\n")
+	fmt.Fprintf(&b, "\nseconds_per_target_function: %d\n", nt.DefaultTestTimeSeconds)
+	fmt.Fprintf(&b, "fuzz_budget_seconds: 0\n")
+	fmt.Fprintf(&b, "offline_modules: false\n")
+	return b.String()
+}
+
+// init_config is "nosy config init", it writes a target config for the
+// repository at a URL or local path
+func init_config(ctx context.Context, source string, output string, force bool) error {
+	repo, err := inspect_repo(ctx, source)
+	if err != nil {
+		return err
+	}
+	name := target_name(source)
+	if output == "" {
+		output = name + ".yaml"
+	}
+	if !strings.HasSuffix(output, ".yaml") {
+		return fmt.Errorf("%w: the target config must be a YAML file (.yaml)", errUsage)
+	}
+	if _, err := os.Stat(output); err == nil && !force {
+		return fmt.Errorf("%s already exists, pass -force to overwrite it", output)
+	}
+	if err := os.WriteFile(output, []byte(repo.render_config(name)), 0o644); err != nil {
+		return err
+	}
+
+	entries, _ := repo.suggest_ignores()
+	fmt.Printf("Wrote %s for %s\n", output, repo.module.ModulePath)
+	fmt.Printf("\t%d packages, %d suggested to ignore\n", len(repo.packages), len(entries))
+	if repo.branch != "" {
+		fmt.Printf("\tbranch %s\n", repo.branch)
+	}
+	fmt.Printf("Review it, then run: nosy run %s\n", output)
+	return validate_config(output)
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	nt "github.com/infosecual/nosy/src/types"
+)
+
+// write_files writes files, keyed by their slash separated path below dir
+func write_files(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// test_repo is a repository with a package of every kind config init tells
+// apart
+var test_repo = map[string]string{
+	"go.mod":                    "module example.com/fake\n\ngo 1.21\n",
+	"fake.go":                   "package fake\n",
+	"parse/parse.go":            "// Package parse parses.\npackage parse\n",
+	"parse/parse_test.go":       "package parse\n",
+	"cmd/fake/main.go":          "package main\n",
+	"tools/gen/main.go":         "package main\n",
+	"api/api.pb.go":             "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n",
+	"api/api.go":                "package api\n",
+	"proto/proto.pb.go":         "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage proto\n",
+	"internal/mocks/db.go":      "package mocks\n",
+	"internal/cmdutil/cmd.go":   "package cmdutil\n",
+	"only_tests/x_test.go":      "package only_tests\n",
+	"docs/README.md":            "docs\n",
+	"vendor/example.com/v/v.go": "package v\n",
+	"parse/testdata/t.go":       "package t\n",
+	".github/x/x.go":            "package x\n",
+	"_old/old.go":               "package old\n",
+	"sub/go.mod":                "module example.com/fake/sub\n",
+	"sub/sub.go":                "package sub\n",
+	"sub/cmd/tool/main.go":      "package main\n",
+	"plugins/go.mod":            "module github.com/other/plugins\n",
+	"plugins/auth/auth.go":      "package auth\n",
+	// not a module, go ignores it
+	"broken/go.mod": "go 1.21\n",
+	"broken/b.go":   "package broken\n",
+}
+
+func inspect_test_repo(t *testing.T, files map[string]string) *inspected_repo {
+	t.Helper()
+	dir := t.TempDir()
+	write_files(t, dir, files)
+	module, err := nt.InferTargetModule(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &inspected_repo{url: "https://example.com/fake.git", local: dir, module: module}
+	if repo.packages, repo.modules, err = find_packages(dir); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestFindPackages(t *testing.T) {
+	repo := inspect_test_repo(t, test_repo)
+	got := map[string]repo_package{}
+	for _, pkg := range repo.packages {
+		got[pkg.dir] = pkg
+	}
+	want := map[string]repo_package{
+		".":                {dir: "."},
+		"parse":            {dir: "parse"},
+		"cmd/fake":         {dir: "cmd/fake", main: true},
+		"tools/gen":        {dir: "tools/gen", main: true},
+		"api":              {dir: "api"},
+		"proto":            {dir: "proto", generated: true},
+		"internal/mocks":   {dir: "internal/mocks"},
+		"internal/cmdutil": {dir: "internal/cmdutil"},
+		"sub":              {dir: "sub"},
+		"sub/cmd/tool":     {dir: "sub/cmd/tool", main: true},
+		"plugins/auth":     {dir: "plugins/auth"},
+		"broken":           {dir: "broken"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("find_packages() = %+v\nwant %+v", got, want)
+	}
+	modules := []repo_module{{"plugins", "github.com/other/plugins"}, {"sub", "example.com/fake/sub"}}
+	if !reflect.DeepEqual(repo.modules, modules) {
+		t.Errorf("find_packages() modules %+v, want %+v", repo.modules, modules)
+	}
+}
+
+func TestImportPath(t *testing.T) {
+	repo := &inspected_repo{
+		module: &nt.TargetModule{ImportPrefix: "example.com/fake", ModulePath: "example.com/fake"},
+		modules: []repo_module{
+			{"sub", "example.com/fake/sub"},
+			{"sub/v2", "example.com/fake/sub/v2"},
+			{"plugins", "github.com/other/plugins"},
+		},
+	}
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{".", "example.com/fake"},
+		{"parse", "example.com/fake/parse"},
+		{"sub", "example.com/fake/sub"},
+		{"sub/cmd/tool", "example.com/fake/sub/cmd/tool"},
+		{"sub/v2/x", "example.com/fake/sub/v2/x"},
+		{"plugins/auth", "github.com/other/plugins/auth"},
+		// a directory sharing a module's prefix is not part of it
+		{"plugins2/auth", "example.com/fake/plugins2/auth"},
+	}
+	for _, tt := range tests {
+		if got := repo.import_path(tt.dir); got != tt.want {
+			t.Errorf("import_path(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestNestedModules(t *testing.T) {
+	modules := []repo_module{{"api", "example.com/ws/api"}, {"sub", "example.com/ws/sub"}}
+	tests := []struct {
+		name      string
+		workspace []string
+		want      []repo_module
+	}{
+		{"module", nil, modules},
+		{"workspace of some", []string{"example.com/ws/api"}, []repo_module{{"sub", "example.com/ws/sub"}}},
+		{"workspace of all", []string{"example.com/ws/sub", "example.com/ws/api"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &inspected_repo{module: &nt.TargetModule{ImportPrefix: "example.com/ws", Modules: tt.workspace}, modules: modules}
+			if got := repo.nested_modules(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("nested_modules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestIgnores(t *testing.T) {
+	tests := []struct {
+		name     string
+		packages []repo_package
+		want     map[string]string
+	}{
+		{
+			name: "kinds",
+			packages: []repo_package{
+				{dir: "parse"},
+				{dir: "cmd/fake", main: true},
+				{dir: "internal/mocks"},
+				{dir: "proto", generated: true},
+				{dir: "scripts/release", main: true},
+			},
+			want: map[string]string{
+				`re:example\.com/fake/cmd(/.*)?`:             "main packages",
+				`re:example\.com/fake/internal/mocks(/.*)?`:  "mocks",
+				`re:example\.com/fake/proto(/.*)?`:           "generated code",
+				`re:example\.com/fake/scripts/release(/.*)?`: "main package",
+			},
+		},
+		{
+			name: "directories below an entry are not listed again",
+			packages: []repo_package{
+				{dir: "tools"},
+				{dir: "tools/gen", main: true},
+				{dir: "tools/gen/proto", generated: true},
+			},
+			want: map[string]string{`re:example\.com/fake/tools(/.*)?`: "build tooling"},
+		},
+		{
+			name:     "never the root",
+			packages: []repo_package{{dir: ".", main: true, generated: true}},
+			want:     map[string]string{},
+		},
+		{
+			name:     "nested module",
+			packages: []repo_package{{dir: "plugins/cmd/x", main: true}},
+			want:     map[string]string{`re:github\.com/other/plugins/cmd(/.*)?`: "main packages"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &inspected_repo{
+				module:   &nt.TargetModule{ImportPrefix: "example.com/fake", ModulePath: "example.com/fake"},
+				packages: tt.packages,
+				modules:  []repo_module{{"plugins", "github.com/other/plugins"}},
+			}
+			entries, reasons := repo.suggest_ignores()
+			if !reflect.DeepEqual(reasons, tt.want) || len(entries) != len(tt.want) {
+				t.Errorf("suggest_ignores() = %q, %v, want %v", entries, reasons, tt.want)
+			}
+		})
+	}
+
+	// entries are anchored, .../cmd does not also ignore .../cmdutil
+	repo := &inspected_repo{
+		module:   &nt.TargetModule{ImportPrefix: "example.com/fake"},
+		packages: []repo_package{{dir: "cmd", main: true}},
+	}
+	entries, _ := repo.suggest_ignores()
+	re := regexp.MustCompile("^(?:" + strings.TrimPrefix(entries[0], "re:") + ")$")
+	for path, match := range map[string]bool{
+		"example.com/fake/cmd":          true,
+		"example.com/fake/cmd/fake":     true,
+		"example.com/fake/cmdutil":      false,
+		"example.com/fake/internal/cmd": false,
+	} {
+		if re.MatchString(path) != match {
+			t.Errorf("%s matches %s: %t, want %t", entries[0], path, !match, match)
+		}
+	}
+}
+
+func TestTargetName(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"https://github.com/ethereum/go-ethereum.git", "go-ethereum"},
+		{"https://github.com/ethereum/go-ethereum", "go-ethereum"},
+		{"git@github.com:ethereum/go-ethereum.git", "go-ethereum"},
+		{"/home/u/src/go-ethereum/", "go-ethereum"},
+		{"/home/u/src/my repo", "my-repo"},
+		{".", "target"},
+		{"/", "target"},
+		{"", "target"},
+	}
+	for _, tt := range tests {
+		if got := target_name(tt.source); got != tt.want {
+			t.Errorf("target_name(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+// a rendered config is valid as it is and holds what inspection found
+func TestRenderConfig(t *testing.T) {
+	repo := inspect_test_repo(t, test_repo)
+	repo.branch = "main"
+	repo.commit = "68840eed5252ee6f23a80cf9e3f5767c9a383cd3"
+	rendered := repo.render_config("fake")
+	path := filepath.Join(t.TempDir(), "fake.yaml")
+	if err := os.WriteFile(path, []byte(rendered), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, check, err := check_config(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(check.Errors) > 0 || len(check.Warnings) > 0 {
+		t.Errorf("rendered config has errors %v and warnings %v:\n%s", check.Errors, check.Warnings, rendered)
+	}
+	if config.TargetRepo != "fake" || config.TargetLocalPath != repo.local || config.TargetRepoBranch != "main" ||
+		config.TargetModuleDeclaration != "example.com/fake" || config.Runner != "docker" {
+		t.Errorf("rendered config %+v", config)
+	}
+	entries, _ := repo.suggest_ignores()
+	if !reflect.DeepEqual(config.IgnorePackages, entries) {
+		t.Errorf("ignore_packages %q, want %q", config.IgnorePackages, entries)
+	}
+	for _, want := range []string{
+		"# one inspected: 68840eed5252ee6f23a80cf9e3f5767c9a383cd3\n",
+		"# the target requires go 1.21\n",
+		"#  - github.com/other/plugins (plugins)\n#  - example.com/fake/sub (sub)\n",
+		"# to fuzz only some of the 12 packages found, list them here:\n",
+		"#  - github.com/other/plugins/auth\n",
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("rendered config lacks %q:\n%s", want, rendered)
+		}
+	}
+}
+
+func TestRenderConfigListedPackages(t *testing.T) {
+	repo := &inspected_repo{module: &nt.TargetModule{ImportPrefix: "example.com/fake", ModulePath: "example.com/fake"}}
+	for i := 0; i < max_listed_packages+3; i++ {
+		repo.packages = append(repo.packages, repo_package{dir: "p" + strings.Repeat("x", i)})
+	}
+	rendered := repo.render_config("fake")
+	if n := strings.Count(rendered, "#  - example.com/fake/p"); n != max_listed_packages {
+		t.Errorf("rendered config lists %d packages, want %d", n, max_listed_packages)
+	}
+	if !strings.Contains(rendered, "#  ... and 3 more\n") {
+		t.Errorf("rendered config does not count the packages left out:\n%s", rendered)
+	}
+	// nothing checked out and no nested modules
+	for _, missing := range []string{"target_local_path", "one inspected", "nested modules", "not part of"} {
+		if strings.Contains(rendered, missing) {
+			t.Errorf("rendered config has %q:\n%s", missing, rendered)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/infosecual/nosy/src/coverage"
+)
+
+// coverage_package returns the -coverpkg pattern matching every package of
+// the target module
+func coverage_package() string {
+	module := TargetConfig.TargetModuleDeclaration
+	if module == "" {
+		module = TargetConfig.TargetRepoImportPrefix
+	}
+	return module + "/..."
+}
+
+// generate_coverage_script writes the script that replays the corpus of every
+// harness with coverage enabled. Each harness gets the inputs of its fuzzing
+// cache and its saved results as seed corpus and writes its profile to
+// /coverage/<harness key>.out. Crashing results kill the test binary before
+// the profile is written, so a harness that fails is replayed again with the
+// cache only.
+func generate_coverage_script(coverage_dir string) error {
+	script := ""
+	script += fmt.Sprintf("echo \"fixing up GOROOT for coverage\"\n")
+	script += fmt.Sprintf("cp -rp /go_backup/. /go\n")
+	for _, h := range Harnesses {
+		harness, key := h.Name, h.Key()
+		corpus := fmt.Sprintf("./testdata/fuzz/%s", harness)
+		// go test keeps the cache of a harness under its name
+		cache := fmt.Sprintf("/cache/%s/%s", key, harness)
+		replay := fmt.Sprintf("%s test -run=^%s$ -covermode=count -coverpkg=%s -coverprofile=/coverage/%s.out > /coverage/%s.log 2>&1",
+			TargetConfig.TargetGoVersion, harness, coverage_package(), key, key)
+		script += fmt.Sprintf("echo \"Replaying the corpus of %s\"\n", key)
+		script += fmt.Sprintf("cd %s\n", h.Directory)
+		script += fmt.Sprintf("rm -rf %s && mkdir -p %s\n", corpus, corpus)
+		script += fmt.Sprintf("cp -r %s/. %s/ 2>/dev/null\n", cache, corpus)
+		script += fmt.Sprintf("cp -r /results/%s/. %s/ 2>/dev/null\n", key, corpus)
+		script += fmt.Sprintf("if ! %s; then\n", replay)
+		script += fmt.Sprintf("\techo \"%s failed, replaying its cache without the saved crashes\"\n", key)
+		script += fmt.Sprintf("\trm -rf %s && mkdir -p %s\n", corpus, corpus)
+		script += fmt.Sprintf("\tcp -r %s/. %s/ 2>/dev/null\n", cache, corpus)
+		script += fmt.Sprintf("\t%s || echo \"%s failed, see %s.log\"\n", replay, key, key)
+		script += fmt.Sprintf("fi\n")
+		script += fmt.Sprintf("rm -rf %s\n", corpus)
+	}
+	return os.WriteFile(filepath.Join(coverage_dir, "coverage.sh"), []byte(script), 0o755)
+}
+
+// generate_coverage_report_script writes the script that renders the merged
+// profile as HTML
+func generate_coverage_report_script(coverage_dir string, docker_repo_path string) error {
+	script := "set -e\n"
+	script += fmt.Sprintf("cd %s\n", docker_repo_path)
+	script += fmt.Sprintf("%s tool cover -html=/coverage/merged.out -o /coverage/coverage.html\n", TargetConfig.TargetGoVersion)
+	return os.WriteFile(filepath.Join(coverage_dir, "coverage_report.sh"), []byte(script), 0o755)
+}
+
+// merge_coverage merges the profiles of every harness that produced one
+func merge_coverage(coverage_dir string) (*coverage.Profile, error) {
+	var merged *coverage.Profile
+	missing := 0
+	for _, h := range Harnesses {
+		harness := h.Key()
+		profile_path := filepath.Join(coverage_dir, harness+".out")
+		if _, err := os.Stat(profile_path); err != nil {
+			fmt.Printf("No coverage for %s, see %s.log\n", harness, filepath.Join(coverage_dir, harness))
+			missing++
+			continue
+		}
+		profile, err := coverage.ParseFile(profile_path)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = coverage.NewProfile(profile.Mode)
+		}
+		if err := merged.Merge(profile); err != nil {
+			return nil, fmt.Errorf("%s: %w", profile_path, err)
+		}
+	}
+	if merged == nil {
+		return nil, fmt.Errorf("no harness produced a coverage profile")
+	}
+	if missing > 0 {
+		fmt.Printf("%d of %d harnesses produced no coverage\n", missing, len(Harnesses))
+	}
+	return merged, nil
+}
+
+// print_coverage writes the per package and per function summaries and lists
+// the fuzzed functions the fuzzer never got past the first statement of
+func print_coverage(w io.Writer, profile *coverage.Profile, functions []coverage.Function) {
+	fmt.Fprintln(w, "\nCoverage by package:")
+	for _, pkg := range profile.Packages() {
+		fmt.Fprintf(w, "\t%-60s %6.1f%%  (%d/%d statements)\n", pkg.Name, pkg.Percent(), pkg.Covered, pkg.Statements)
+	}
+
+	// harnesses by the function they wrap, harnesses listed without one
+	// in an old fuzzable.txt are matched by their name
+	harnesses := map[string]string{}
+	for _, h := range Harnesses {
+		name := h.Function
+		if h.Receiver != "" {
+			name = h.Receiver + "." + h.Function
+		}
+		if h.Package == "" {
+			harnesses[h.Name] = h.Name
+			continue
+		}
+		harnesses[h.Package+"."+name] = h.Name
+	}
+	type stuck_function struct {
+		coverage.Function
+		harness string
+	}
+	var stuck []stuck_function
+	fmt.Fprintln(w, "\nCoverage by function:")
+	for _, f := range functions {
+		fuzzed := " "
+		harness, ok := harnesses[f.Package+"."+f.Name]
+		if !ok {
+			harness, ok = harnesses[f.Harness()]
+		}
+		if ok {
+			fuzzed = "*"
+			if f.Stuck() {
+				stuck = append(stuck, stuck_function{f, harness})
+			}
+		}
+		fmt.Fprintf(w, "\t%s %-60s %6.1f%%  (%d/%d statements)\n", fuzzed,
+			f.Package+"."+f.Name, f.Percent(), f.Covered, f.Statements)
+	}
+	fmt.Fprintln(w, "\t(* has a harness)")
+
+	if len(stuck) == 0 {
+		fmt.Fprintln(w, "\nEvery fuzzed function got past its first statement")
+		return
+	}
+	fmt.Fprintf(w, "\n%d fuzzed functions never got past their first statement:\n", len(stuck))
+	for _, f := range stuck {
+		reason := "stopped in its first block"
+		if !f.Reached {
+			reason = "never reached"
+		}
+		fmt.Fprintf(w, "\t%-40s %s:%d  %s\n", f.harness, f.File, f.Line, reason)
+	}
+}
+
+// coverage_report is the "nosy coverage" command, it replays the corpus of
+// every harness with coverage enabled, merges the profiles and summarizes
+// them per package and per function next to an HTML view
+func coverage_report(ctx context.Context) error {
+	// get pwd for subsequent commands
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
+	local_goroot_path := target_dir + "/go"
+	local_repo_path := fmt.Sprintf("%s/src/%s", local_goroot_path, TargetConfig.TargetRepoImportPrefix)
+	docker_repo_path := fmt.Sprintf("/go/src/%s", TargetConfig.TargetRepoImportPrefix)
+	local_cache_path := target_dir + "/cache"
+	local_results_path := target_dir + "/results"
+	coverage_dir := target_dir + "/coverage"
+
+	if err := load_harnesses(local_repo_path, local_goroot_path); err != nil {
+		return err
+	}
+	// start from scratch so profiles of removed harnesses are not merged
+	if err := os.RemoveAll(coverage_dir); err != nil {
+		return err
+	}
+	for _, dir := range []string{coverage_dir, local_cache_path, local_results_path} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if err := generate_coverage_script(coverage_dir); err != nil {
+		return err
+	}
+	if err := generate_coverage_report_script(coverage_dir, docker_repo_path); err != nil {
+		return err
+	}
+
+	fmt.Println("Replaying the corpus of", len(Harnesses), "harnesses with coverage...")
+	spec := RunSpec{
+		Name:    "coverage.sh",
+		Script:  "coverage.sh",
+		Workdir: "/coverage",
+		Mounts: []Mount{
+			{HostPath: coverage_dir, ContainerPath: "/coverage"},
+			{HostPath: local_goroot_path, ContainerPath: "/go_backup"},
+			{HostPath: local_cache_path, ContainerPath: "/cache"},
+			{HostPath: local_results_path, ContainerPath: "/results"},
+		},
+	}
+	if err := record_step(spec.Name, ActiveRunner.Run(ctx, spec)); err != nil {
+		return fmt.Errorf("%s: %w", spec.Name, err)
+	}
+
+	merged, err := merge_coverage(coverage_dir)
+	if err != nil {
+		return err
+	}
+	merged_path := filepath.Join(coverage_dir, "merged.out")
+	if err := merged.WriteFile(merged_path); err != nil {
+		return err
+	}
+
+	// profiles name files by import path, the sources live under the host
+	// copy of the repo
+	module := strings.TrimSuffix(coverage_package(), "/...")
+	functions, err := merged.Functions(func(file string) (string, bool) {
+		if !strings.HasPrefix(file, module+"/") {
+			return "", false
+		}
+		return filepath.Join(local_repo_path, strings.TrimPrefix(file, module+"/")), true
+	})
+	if err != nil {
+		return err
+	}
+
+	summary, err := os.Create(filepath.Join(coverage_dir, "summary.txt"))
+	if err != nil {
+		return err
+	}
+	print_coverage(io.MultiWriter(os.Stdout, summary), merged, functions)
+	if err := summary.Close(); err != nil {
+		return err
+	}
+
+	report := RunSpec{
+		Name:    "coverage_report.sh",
+		Script:  "/coverage/coverage_report.sh",
+		Workdir: docker_repo_path,
+		Mounts: []Mount{
+			{HostPath: local_goroot_path, ContainerPath: "/go"},
+			{HostPath: coverage_dir, ContainerPath: "/coverage"},
+		},
+	}
+	if err := record_step(report.Name, ActiveRunner.Run(ctx, report)); err != nil {
+		return fmt.Errorf("%s: %w", report.Name, err)
+	}
+	fmt.Printf("\nMerged profile: %s\n", merged_path)
+	fmt.Printf("HTML view:      %s\n", filepath.Join(coverage_dir, "coverage.html"))
+	fmt.Printf("Summary:        %s\n", filepath.Join(coverage_dir, "summary.txt"))
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// number of trailing stderr lines kept for error reports
+const stderr_tail_lines = 20
+
+// CommandError describes an external command that exited unsuccessfully
+type CommandError struct {
+	Command  string
+	ExitCode int
+	// the last few lines the command wrote to stderr
+	StderrTail string
+	Err        error
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("command %q exited with code %d", e.Command, e.ExitCode)
+	if e.StderrTail != "" {
+		msg += "\n\tstderr:\n\t\t" + strings.ReplaceAll(e.StderrTail, "\n", "\n\t\t")
+	}
+	return msg
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// tail_writer keeps the last n lines written to it
+type tail_writer struct {
+	n       int
+	lines   []string
+	partial string
+}
+
+func (t *tail_writer) Write(p []byte) (int, error) {
+	s := t.partial + string(p)
+	parts := strings.Split(s, "\n")
+	t.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		t.lines = append(t.lines, line)
+	}
+	if len(t.lines) > t.n {
+		t.lines = t.lines[len(t.lines)-t.n:]
+	}
+	return len(p), nil
+}
+
+func (t *tail_writer) String() string {
+	lines := t.lines
+	if t.partial != "" {
+		lines = append(lines, t.partial)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// exec_and_print runs a shell command, streaming its output to the terminal,
+// and returns a *CommandError if it could not be run or exited non-zero
+func exec_and_print(command string) error {
+	fmt.Println(command)
+	return run_and_capture(exec.Command("bash", "-c", command), command)
+}
+
+// run_and_capture runs cmd, keeping the tail of its stderr for error reports.
+// Output goes to the terminal unless cmd already has writers attached.
+// display is how the command is shown in errors.
+func run_and_capture(cmd *exec.Cmd, display string) error {
+	return run_and_capture_ctx(context.Background(), cmd, display, nil)
+}
+
+// run_and_capture_ctx is run_and_capture for commands that can be
+// interrupted. When ctx is cancelled on_cancel is called to stop cmd and
+// ctx's error is returned once it exited.
+func run_and_capture_ctx(ctx context.Context, cmd *exec.Cmd, display string, on_cancel func()) error {
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	tail := &tail_writer{n: stderr_tail_lines}
+	combined := cmd.Stdout == cmd.Stderr
+	cmd.Stderr = &multi_writer{cmd.Stderr, tail}
+	if combined {
+		// exec only copies both streams with one goroutine while they are
+		// the same writer, two would write to it at once
+		cmd.Stdout = cmd.Stderr
+	}
+
+	err := ctx.Err()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err == nil {
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				if on_cancel != nil {
+					on_cancel()
+				}
+			case <-done:
+			}
+		}()
+		err = cmd.Wait()
+		close(done)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == nil {
+		return nil
+	}
+	exit_code := -1
+	var exit_err *exec.ExitError
+	if errors.As(err, &exit_err) {
+		exit_code = exit_err.ExitCode()
+	}
+	return &CommandError{
+		Command:    strings.TrimSpace(display),
+		ExitCode:   exit_code,
+		StderrTail: tail.String(),
+		Err:        err,
+	}
+}
+
+// multi_writer writes to every writer, ignoring errors from all but the first
+// so a broken terminal does not hide the captured output or vice versa
+type multi_writer []io.Writer
+
+func (m multi_writer) Write(p []byte) (int, error) {
+	n, err := m[0].Write(p)
+	for _, w := range m[1:] {
+		w.Write(p)
+	}
+	return n, err
+}
+
+// step_result records the outcome of one step of a nosy stage
+type step_result struct {
+	name string
+	err  error
+}
+
+var step_results []step_result
+var step_results_mu sync.Mutex
+
+// record_step remembers the outcome of a step for the end of run summary and
+// returns err so it can be used inline
+func record_step(name string, err error) error {
+	step_results_mu.Lock()
+	defer step_results_mu.Unlock()
+	step_results = append(step_results, step_result{name: name, err: err})
+	return err
+}
+
+// run_step runs a shell command as a named step of a stage
+func run_step(name string, command string) error {
+	if err := record_step(name, exec_and_print(command)); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// print_step_summary lists every step that ran and which of them failed
+func print_step_summary() {
+	step_results_mu.Lock()
+	defer step_results_mu.Unlock()
+	if len(step_results) == 0 {
+		return
+	}
+	failed := 0
+	fmt.Println()
+	fmt.Println("Summary:")
+	for _, step := range step_results {
+		if step.err == nil {
+			fmt.Printf("\tok\t%s\n", step.name)
+			continue
+		}
+		failed++
+		var cmd_err *CommandError
+		if errors.As(step.err, &cmd_err) {
+			fmt.Printf("\tFAILED\t%s (exit code %d)\n", step.name, cmd_err.ExitCode)
+		} else {
+			fmt.Printf("\tFAILED\t%s (%v)\n", step.name, step.err)
+		}
+	}
+	fmt.Printf("%d of %d steps failed\n", failed, len(step_results))
+}
@@ -4,12 +4,12 @@ go 1.19
 
 require (
 	github.com/infosecual/go-fuzz-fill-utils v0.0.0-20220927195354-abd331257491
+	golang.org/x/mod v0.6.0
 	golang.org/x/tools v0.2.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
 	github.com/sanity-io/litter v1.5.5 // indirect
-	golang.org/x/mod v0.6.0 // indirect
 	golang.org/x/sys v0.1.0 // indirect
 )
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/infosecual/nosy/src/campaign"
+	"github.com/infosecual/nosy/src/triage"
+)
+
+// campaign_metrics serves the progress of a campaign as Prometheus text
+// format metrics. Harness stats come from the campaign state, which the fuzz
+// stage feeds from go test's progress lines, and crashes are bucketed as
+// each harness finishes.
+type campaign_metrics struct {
+	state *campaign.State
+
+	mu      sync.Mutex
+	buckets *triage.Buckets
+	crashes map[string]int
+}
+
+func new_campaign_metrics(state *campaign.State, buckets *triage.Buckets) *campaign_metrics {
+	return &campaign_metrics{state: state, buckets: buckets, crashes: map[string]int{}}
+}
+
+// add_output buckets the crashes in the output of a finished harness run
+func (m *campaign_metrics) add_output(output []byte) {
+	crashes, err := triage.Parse(bytes.NewReader(output))
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range crashes {
+		bucket, _ := m.buckets.Add(&crashes[i])
+		m.crashes[bucket.ID]++
+	}
+}
+
+// metric_label escapes a label value as the text format requires
+func metric_label(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// write writes every metric in the Prometheus text format
+func (m *campaign_metrics) write(w io.Writer) {
+	target := fmt.Sprintf(`target="%s"`, metric_label(TargetConfig.TargetRepo))
+	metric := func(name string, kind string, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	counts := map[campaign.Status]int{}
+	finished := 0
+	var execs int64
+	var rate float64
+	var budget *campaign.Budget
+	m.state.View(func(s *campaign.State) {
+		for _, h := range s.Harnesses {
+			counts[h.Status]++
+			if h.Status.Finished() {
+				finished++
+			}
+			execs += h.TotalExecs
+			// running harnesses only add to TotalExecs once they finish
+			if h.Status == campaign.Running && h.Stats != nil {
+				execs += h.Stats.Execs
+				rate += h.Stats.ExecsPerSec
+			}
+		}
+		if s.Budget != nil {
+			copied := *s.Budget
+			budget = &copied
+		}
+	})
+
+	metric("nosy_harnesses", "gauge", "Harnesses of the campaign by status.")
+	for _, s := range []campaign.Status{campaign.Pending, campaign.Running, campaign.Done, campaign.Crashed, campaign.BuildFailed} {
+		fmt.Fprintf(w, "nosy_harnesses{%s,status=\"%s\"} %d\n", target, s, counts[s])
+	}
+	metric("nosy_harnesses_completed", "gauge", "Harnesses that need no more fuzzing in this campaign.")
+	fmt.Fprintf(w, "nosy_harnesses_completed{%s} %d\n", target, finished)
+	metric("nosy_active_containers", "gauge", "Harness runs in progress.")
+	fmt.Fprintf(w, "nosy_active_containers{%s} %d\n", target, counts[campaign.Running])
+	metric("nosy_build_failures", "gauge", "Harnesses whose fuzzer failed to build.")
+	fmt.Fprintf(w, "nosy_build_failures{%s} %d\n", target, counts[campaign.BuildFailed])
+	metric("nosy_execs_total", "counter", "Fuzzing executions reported by go test.")
+	fmt.Fprintf(w, "nosy_execs_total{%s} %d\n", target, execs)
+	metric("nosy_execs_per_second", "gauge", "Executions per second of the running harnesses.")
+	fmt.Fprintf(w, "nosy_execs_per_second{%s} %g\n", target, rate)
+	if budget != nil {
+		metric("nosy_budget_seconds", "gauge", "Fuzzing time budget of the campaign.")
+		fmt.Fprintf(w, "nosy_budget_seconds{%s} %d\n", target, budget.TotalSeconds)
+		metric("nosy_budget_spent_seconds", "gauge", "Fuzzing time handed out to harnesses so far.")
+		fmt.Fprintf(w, "nosy_budget_spent_seconds{%s} %d\n", target, budget.SpentSeconds)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for id := range m.crashes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	metric("nosy_crash_buckets", "gauge", "Crash buckets hit during this campaign.")
+	fmt.Fprintf(w, "nosy_crash_buckets{%s} %d\n", target, len(ids))
+	metric("nosy_crashes_total", "counter", "Crashes found during this campaign by bucket.")
+	for _, id := range ids {
+		fmt.Fprintf(w, "nosy_crashes_total{%s,bucket=\"%s\",panic=\"%s\"} %d\n", target, id,
+			metric_label(m.buckets.Buckets[id].Panic), m.crashes[id])
+	}
+}
+
+// serve_metrics starts serving the metrics on addr under /metrics until the
+// returned stop function is called
+func serve_metrics(addr string, m *campaign_metrics) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics listener: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.write(w)
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(os.Stderr, "nosy: warning: metrics listener stopped:", err)
+		}
+	}()
+	fmt.Printf("Serving metrics on http://%s/metrics\n", listener.Addr())
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
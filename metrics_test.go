@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/infosecual/nosy/src/campaign"
+	"github.com/infosecual/nosy/src/triage"
+)
+
+func TestMetricLabel(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"go-ethereum", "go-ethereum"},
+		{`index out of range [48] with length 4`, `index out of range [48] with length 4`},
+		{`unexpected "x"`, `unexpected \"x\"`},
+		{`C:\go`, `C:\\go`},
+		{"header of 6 bytes\nis too long", `header of 6 bytes\nis too long`},
+	}
+	for _, tt := range tests {
+		if got := metric_label(tt.value); got != tt.want {
+			t.Errorf("metric_label(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// test_metrics returns the metrics of a campaign with a harness in every
+// status
+func test_metrics(t *testing.T, budget *campaign.Budget) *campaign_metrics {
+	t.Helper()
+	s := campaign.New(filepath.Join(t.TempDir(), campaign.StateFile), "fake")
+	err := s.Update(func(s *campaign.State) {
+		for _, key := range []string{"pending", "running", "done", "crashed", "build-failed"} {
+			s.AddHarness(key, key, "parse")
+		}
+		s.Harness("running").Status = campaign.Running
+		s.Harness("running").TotalExecs = 100
+		s.Harness("running").Stats = &campaign.Stats{Execs: 50, ExecsPerSec: 12.5}
+		s.Harness("done").Status = campaign.Done
+		s.Harness("done").TotalExecs = 1000
+		s.Harness("crashed").Status = campaign.Crashed
+		s.Harness("crashed").TotalExecs = 7
+		s.Harness("build-failed").Status = campaign.BuildFailed
+		s.Budget = budget
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return new_campaign_metrics(s, triage.NewBuckets(5))
+}
+
+func TestMetricsWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget *campaign.Budget
+		// crash logs fed to the metrics, from src/triage/testdata
+		logs    []string
+		want    []string
+		missing []string
+	}{
+		{
+			name: "no crashes",
+			want: []string{
+				`nosy_harnesses{target="fake",status="pending"} 1`,
+				`nosy_harnesses{target="fake",status="running"} 1`,
+				`nosy_harnesses{target="fake",status="done"} 1`,
+				`nosy_harnesses{target="fake",status="crashed"} 1`,
+				`nosy_harnesses{target="fake",status="build-failed"} 1`,
+				`nosy_harnesses_completed{target="fake"} 3`,
+				`nosy_active_containers{target="fake"} 1`,
+				`nosy_build_failures{target="fake"} 1`,
+				`nosy_execs_total{target="fake"} 1157`,
+				`nosy_execs_per_second{target="fake"} 12.5`,
+				`nosy_crash_buckets{target="fake"} 0`,
+				"# TYPE nosy_execs_total counter",
+			},
+			missing: []string{"nosy_budget_seconds", "nosy_crashes_total{"},
+		},
+		{
+			name:   "budget",
+			budget: &campaign.Budget{TotalSeconds: 3600, SpentSeconds: 600},
+			want: []string{
+				`nosy_budget_seconds{target="fake"} 3600`,
+				`nosy_budget_spent_seconds{target="fake"} 600`,
+			},
+		},
+		{
+			name: "crashes",
+			logs: []string{"divide.log", "divide.log", "index.log"},
+			want: []string{
+				`nosy_crash_buckets{target="fake"} 2`,
+				`,panic="runtime error: integer divide by zero"} 2`,
+				`,panic="runtime error: index out of range [48] with length 4"} 1`,
+			},
+		},
+		{
+			name: "no crash in the output",
+			logs: []string{"pass.log"},
+			want: []string{`nosy_crash_buckets{target="fake"} 0`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := test_metrics(t, tt.budget)
+			for _, log := range tt.logs {
+				output, err := os.ReadFile(filepath.Join("src", "triage", "testdata", log))
+				if err != nil {
+					t.Fatal(err)
+				}
+				m.add_output(output)
+			}
+			defer func(repo string) { TargetConfig.TargetRepo = repo }(TargetConfig.TargetRepo)
+			TargetConfig.TargetRepo = "fake"
+			var out bytes.Buffer
+			m.write(&out)
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("metrics lack %q:\n%s", want, out.String())
+				}
+			}
+			for _, missing := range tt.missing {
+				if strings.Contains(out.String(), missing) {
+					t.Errorf("metrics have %q:\n%s", missing, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	m := test_metrics(t, nil)
+	var stop func()
+	out := capture_stdout(t, func() {
+		var err error
+		if stop, err = serve_metrics("127.0.0.1:0", m); err != nil {
+			t.Fatal(err)
+		}
+	})
+	defer stop()
+	url := strings.TrimSpace(strings.TrimPrefix(out, "Serving metrics on "))
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("GET %s: %s, %s", url, resp.Status, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "nosy_harnesses_completed") {
+		t.Errorf("GET %s returned\n%s", url, body)
+	}
+}
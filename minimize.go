@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/infosecual/nosy/src/minimize"
+	"github.com/infosecual/nosy/src/triage"
+)
+
+// how long a single candidate may run before it counts as a hang
+const minimize_timeout_seconds = 10
+
+// minimize_options configures the minimize stage
+type minimize_options struct {
+	// candidates tried per run of the oracle
+	batch int
+	// maximum number of oracle runs per crash
+	rounds int
+}
+
+// generate_minimize_script writes the oracle script for a harness. It builds
+// the harness's test binary on its first run and then runs every candidate
+// under /work/testdata/fuzz, saving the output of each to /work/out.
+func generate_minimize_script(work_dir string, harness string, harness_dir string) error {
+	script := ""
+	script += fmt.Sprintf("set -e\n")
+	script += fmt.Sprintf("if [ ! -x /work/harness.test ]; then\n")
+	script += fmt.Sprintf("\techo \"building the test binary of %s\"\n", harness)
+	script += fmt.Sprintf("\tcp -rp /go_backup/. /go\n")
+	script += fmt.Sprintf("\tcd %s\n", harness_dir)
+	script += fmt.Sprintf("\t%s test -c -o /work/harness.test\n", TargetConfig.TargetGoVersion)
+	script += fmt.Sprintf("fi\n")
+	script += fmt.Sprintf("cd /work\n")
+	script += fmt.Sprintf("for input in ./testdata/fuzz/%s/*; do\n", harness)
+	script += fmt.Sprintf("\t[ -e \"$input\" ] || continue\n")
+	script += fmt.Sprintf("\tname=$(basename $input)\n")
+	script += fmt.Sprintf("\t./harness.test -test.run=\"^%s\\$/^$name\\$\" -test.timeout=%ds > ./out/$name.log 2>&1 || true\n",
+		harness, minimize_timeout_seconds)
+	script += fmt.Sprintf("done\n")
+	return os.WriteFile(filepath.Join(work_dir, "minimize.sh"), []byte(script), 0o755)
+}
+
+// crash_oracle runs candidate inputs of a harness and reports which of them
+// still crash into the same bucket
+type crash_oracle struct {
+	name    string
+	harness string
+	bucket  string
+	frames  int
+	work    string
+	mounts  []Mount
+}
+
+// run reports for every candidate whether it hits the oracle's bucket
+func (o *crash_oracle) run(ctx context.Context, candidates []minimize.Input) ([]bool, error) {
+	corpus_dir := filepath.Join(o.work, "testdata", "fuzz", o.harness)
+	out_dir := filepath.Join(o.work, "out")
+	for _, dir := range []string{corpus_dir, out_dir} {
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	for i, c := range candidates {
+		if err := os.WriteFile(filepath.Join(corpus_dir, fmt.Sprintf("c%04d", i)), c.Marshal(), 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	var output bytes.Buffer
+	err := ActiveRunner.Run(ctx, RunSpec{
+		Name:    o.name,
+		Script:  "minimize.sh",
+		Workdir: "/work",
+		Mounts:  o.mounts,
+		Stdout:  &output,
+		Stderr:  &output,
+	})
+	if err != nil {
+		os.Stdout.Write(output.Bytes())
+		return nil, err
+	}
+
+	hits := make([]bool, len(candidates))
+	for i := range candidates {
+		crashes, err := triage.ParseFile(filepath.Join(out_dir, fmt.Sprintf("c%04d.log", i)))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range crashes {
+			if triage.BucketID(c.Signature(o.frames)) == o.bucket {
+				hits[i] = true
+			}
+		}
+	}
+	return hits, nil
+}
+
+// minimize_crash shrinks the representative input of a bucket for as long as
+// it keeps crashing into the bucket and writes the result next to it
+func minimize_crash(ctx context.Context, target_dir string, bucket *triage.Bucket, frames int,
+	harness string, harness_dir string, opts minimize_options) (string, error) {
+
+	data, err := os.ReadFile(bucket.Representative)
+	if err != nil {
+		return "", err
+	}
+	input, err := minimize.Parse(data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", bucket.Representative, err)
+	}
+
+	// the work directory holds the test binary, the candidates and their
+	// output, and is thrown away once the crash is minimized
+	work := filepath.Join(target_dir, "minimize", bucket.ID)
+	if err := os.RemoveAll(work); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(work, 0o755); err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(work)
+	if err := generate_minimize_script(work, harness, harness_dir); err != nil {
+		return "", err
+	}
+	oracle := &crash_oracle{
+		name:    "minimize " + bucket.ID,
+		harness: harness,
+		bucket:  bucket.ID,
+		frames:  frames,
+		work:    work,
+		mounts: []Mount{
+			{HostPath: work, ContainerPath: "/work"},
+			{HostPath: filepath.Join(target_dir, "go"), ContainerPath: "/go_backup"},
+		},
+	}
+
+	hits, err := oracle.run(ctx, []minimize.Input{input})
+	if err != nil {
+		return "", err
+	}
+	if !hits[0] {
+		return "", fmt.Errorf("%s no longer crashes into bucket %s", bucket.Representative, bucket.ID)
+	}
+
+	m := minimize.New(input)
+	for round := 1; round <= opts.rounds && !m.Done(); round++ {
+		batch := m.Next(opts.batch)
+		if len(batch) == 0 {
+			break
+		}
+		hits, err := oracle.run(ctx, batch)
+		if err != nil {
+			return "", err
+		}
+		var smallest minimize.Input
+		for i, hit := range hits {
+			if hit && (smallest == nil || minimize.Less(batch[i], smallest)) {
+				smallest = batch[i]
+			}
+		}
+		if smallest != nil {
+			m.Accept(smallest)
+			fmt.Printf("\tround %d: %d bytes\n", round, smallest.Size())
+		}
+	}
+
+	minimized := bucket.Representative + ".min"
+	if err := os.WriteFile(minimized, m.Best().Marshal(), 0o644); err != nil {
+		return "", err
+	}
+	fmt.Printf("Minimized %s from %d to %d bytes: %s\n", bucket.ID, input.Size(), m.Best().Size(), minimized)
+	return minimized, nil
+}
+
+// minimize_crashes is the "nosy minimize" command, it minimizes the inputs of
+// the given triage buckets, or of every bucket if none are given
+func minimize_crashes(ctx context.Context, bucket_ids []string, opts minimize_options) error {
+	// get pwd for subsequent commands
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
+	local_goroot_path := target_dir + "/go"
+	local_repo_path := fmt.Sprintf("%s/src/%s", local_goroot_path, TargetConfig.TargetRepoImportPrefix)
+	buckets_path := filepath.Join(target_dir, "triage", "buckets.json")
+
+	if _, err := os.Stat(buckets_path); err != nil {
+		return fmt.Errorf("no crash buckets, run \"nosy fuzz\" first: %w", err)
+	}
+	buckets, err := triage.LoadBuckets(buckets_path, triage.DefaultFrames)
+	if err != nil {
+		return err
+	}
+	if err := load_harnesses(local_repo_path, local_goroot_path); err != nil {
+		return err
+	}
+
+	selected := buckets.Sorted()
+	if len(bucket_ids) > 0 {
+		selected = nil
+		for _, id := range bucket_ids {
+			bucket, ok := buckets.Buckets[id]
+			if !ok {
+				return fmt.Errorf("%w: unknown crash bucket %s", errUsage, id)
+			}
+			selected = append(selected, bucket)
+		}
+	}
+
+	failed := 0
+	for _, bucket := range selected {
+		if bucket.Representative == "" {
+			fmt.Printf("Skipping %s, no input was saved for it\n", bucket.ID)
+			continue
+		}
+		key := filepath.Base(filepath.Dir(bucket.Representative))
+		fmt.Printf("\nMinimizing %s (%s): %s\n", bucket.ID, key, bucket.Panic)
+		minimized, err := "", error(nil)
+		if h, ok := find_harness(key); ok {
+			minimized, err = minimize_crash(ctx, target_dir, bucket, buckets.Frames, h.Name, h.Directory, opts)
+		} else {
+			err = fmt.Errorf("%s is not one of the target's harnesses", key)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("minimization interrupted: %w", ctx.Err())
+		}
+		if record_step("minimize "+bucket.ID, err) != nil {
+			fmt.Fprintf(os.Stderr, "nosy: minimizing %s failed: %v\n", bucket.ID, err)
+			failed++
+			continue
+		}
+		bucket.Minimized = minimized
+		if err := buckets.Save(buckets_path); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d minimizations failed", failed, len(selected))
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// directory of the target's module proxy, in the target directory, and
+// where it is mounted for the scripts
+const (
+	module_proxy_dir   = "modproxy"
+	module_proxy_mount = "/modproxy"
+)
+
+// generate_module_proxy_script writes the script that downloads every module
+// the target, its tests, the harnesses and parse-package need and lays them
+// out as a GOPROXY in the target's module proxy directory. The target's go.mod
+// and go.sum are left as they were.
+func generate_module_proxy_script(target_dir string) error {
+	script := fmt.Sprintf("REPO_PREFIX=\"%s\"\n", TargetConfig.TargetRepoImportPrefix)
+	script += `set -e
+cd /go/src/$REPO_PREFIX
+BACKUP=$(mktemp -d)
+cp go.mod $BACKUP/
+if [ -f go.sum ]; then cp go.sum $BACKUP/; fi
+restore() {
+	cp $BACKUP/go.mod go.mod
+	if [ -f $BACKUP/go.sum ]; then cp $BACKUP/go.sum go.sum; else rm -f go.sum; fi
+	rm -rf $BACKUP
+}
+trap restore EXIT
+`
+	for _, dep := range harness_gen_deps() {
+		script += dep + "\n"
+	}
+	// init already fetched the packages of the target and its tests, add
+	// those of parse-package
+	script += "go list -deps " + parse_package_files + " > /dev/null\n"
+	script += `rm -rf /staging/` + module_proxy_dir + `
+mkdir -p /staging/` + module_proxy_dir + `
+cp -r /go/pkg/mod/cache/download/. /staging/` + module_proxy_dir + `/
+echo "module proxy holds $(find /staging/` + module_proxy_dir + ` -name '*.zip' | wc -l) module versions"
+`
+	return os.WriteFile(filepath.Join(target_dir, "fetch_modules.sh"), []byte(script), 0o755)
+}
+
+// fetch_modules fills the module proxy of an offline target, it is the one
+// step of offline mode that needs the network
+func fetch_modules(ctx context.Context, target_dir string) error {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nFetching the modules of the target for offline use...")
+	if err := generate_module_proxy_script(target_dir); err != nil {
+		return err
+	}
+	spec := RunSpec{
+		Name:   "fetch_modules.sh",
+		Script: "/staging/fetch_modules.sh",
+		Mounts: []Mount{
+			{HostPath: target_dir, ContainerPath: "/staging"},
+			{HostPath: target_dir + "/go", ContainerPath: "/go"},
+			{HostPath: pwd + "/src", ContainerPath: "/src"},
+		},
+	}
+	if err := record_step(spec.Name, ActiveRunner.Run(ctx, spec)); err != nil {
+		return fmt.Errorf("%s: %w", spec.Name, err)
+	}
+	return nil
+}
+
+// proxy_runner runs every script of an offline target against the target's
+// module proxy, so the go command never reaches the network
+type proxy_runner struct {
+	Runner
+	// proxy is the module proxy directory on the host
+	proxy string
+}
+
+func (r *proxy_runner) Run(ctx context.Context, spec RunSpec) error {
+	proxy := "file://" + module_proxy_mount
+	if r.Runner.Name() == "local" {
+		// local scripts see the host's paths
+		proxy = "file://" + r.proxy
+	} else {
+		spec.Mounts = append(spec.Mounts, Mount{HostPath: r.proxy, ContainerPath: module_proxy_mount})
+	}
+	// modules matching GOPRIVATE or GONOPROXY would be fetched directly
+	spec.Env = append(spec.Env, "GOFLAGS=-mod=mod", "GOPROXY="+proxy, "GONOSUMDB=*", "GONOPROXY=", "GOPRIVATE=")
+	return r.Runner.Run(ctx, spec)
+}
+
+// use_module_proxy switches the active runner to the target's module proxy
+// when the target is fuzzed offline
+func use_module_proxy() error {
+	if !TargetConfig.OfflineModules {
+		return nil
+	}
+	if _, ok := ActiveRunner.(*proxy_runner); ok {
+		return nil
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	proxy := fmt.Sprintf("%s/fuzzing_directory/%s/%s", pwd, TargetConfig.TargetRepo, module_proxy_dir)
+	if _, err := os.Stat(proxy); err != nil {
+		return fmt.Errorf("target %s has no module proxy for offline_modules, run \"nosy init\" or \"nosy update\" with network access first", TargetConfig.TargetRepo)
+	}
+	ActiveRunner = &proxy_runner{Runner: ActiveRunner, proxy: proxy}
+	return nil
+}
+
+// count_module_versions returns how many module versions the module proxy
+// at dir serves
+func count_module_versions(dir string) int {
+	count := 0
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(path, ".zip") {
+			count++
+		}
+		return nil
+	})
+	return count
+}
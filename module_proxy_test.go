@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	nt "github.com/infosecual/nosy/src/types"
+)
+
+func TestGenerateModuleProxyScript(t *testing.T) {
+	defer func(c nt.TargetRepoConfig) { TargetConfig = c }(TargetConfig)
+	TargetConfig = nt.TargetRepoConfig{TargetRepoImportPrefix: "example.com/fake", HarnessGenDeps: []string{"go get gopkg.in/yaml.v2"}}
+	dir := t.TempDir()
+	if err := generate_module_proxy_script(dir); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "fetch_modules.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := string(data)
+	for _, want := range []string{
+		"REPO_PREFIX=\"example.com/fake\"\n",
+		"trap restore EXIT\n",
+		"go get github.com/infosecual/go-fuzz-fill-utils/fuzzer\n",
+		"go get gopkg.in/yaml.v2\n",
+		"go list -deps " + parse_package_files + " > /dev/null\n",
+		"cp -r /go/pkg/mod/cache/download/. /staging/" + module_proxy_dir + "/\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script lacks %q:\n%s", want, script)
+		}
+	}
+	// the go.mod is restored before the dependencies are fetched
+	if strings.Index(script, "trap restore EXIT") > strings.Index(script, "go get") {
+		t.Errorf("script fetches dependencies before it can restore go.mod:\n%s", script)
+	}
+}
+
+// parse_package_files names every source of parse-package but none of its
+// tests, which go run and go list -deps would refuse
+func TestParsePackageFiles(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	src, err := filepath.Abs("src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := rewrite_container_paths("echo "+parse_package_files+"\n", []Mount{{HostPath: src, ContainerPath: "/src"}})
+	out, err := exec.Command("bash", "-c", script).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := strings.Fields(string(out))
+	want, err := filepath.Glob(filepath.Join(src, "cmd", "parse-package", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := 0
+	for _, file := range want {
+		if strings.HasSuffix(file, "_test.go") {
+			tests++
+		}
+	}
+	if tests == 0 || len(files) != len(want)-tests {
+		t.Errorf("%s expands to %d of %d files, %d tests:\n%s", parse_package_files, len(files), len(want), tests, out)
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			t.Errorf("%s expands to the test %s", parse_package_files, file)
+		}
+	}
+}
+
+// spec_runner records the spec of the last run
+type spec_runner struct {
+	name string
+	spec RunSpec
+}
+
+func (r *spec_runner) Name() string                       { return r.name }
+func (r *spec_runner) BuildImage(dockerfile string) error { return nil }
+
+func (r *spec_runner) Run(ctx context.Context, spec RunSpec) error {
+	r.spec = spec
+	return nil
+}
+
+func TestProxyRunner(t *testing.T) {
+	mount := Mount{HostPath: "/home/u/nosy/fuzzing_directory/fake", ContainerPath: "/staging"}
+	proxy := "/home/u/nosy/fuzzing_directory/fake/" + module_proxy_dir
+	tests := []struct {
+		runner string
+		mounts []Mount
+		proxy  string
+	}{
+		{"docker", []Mount{mount, {HostPath: proxy, ContainerPath: module_proxy_mount}}, "GOPROXY=file://" + module_proxy_mount},
+		{"podman", []Mount{mount, {HostPath: proxy, ContainerPath: module_proxy_mount}}, "GOPROXY=file://" + module_proxy_mount},
+		{"local", []Mount{mount}, "GOPROXY=file://" + proxy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.runner, func(t *testing.T) {
+			inner := &spec_runner{name: tt.runner}
+			r := &proxy_runner{Runner: inner, proxy: proxy}
+			if r.Name() != tt.runner {
+				t.Errorf("Name() = %s, want %s", r.Name(), tt.runner)
+			}
+			err := r.Run(context.Background(), RunSpec{Name: "fuzz.sh", Mounts: []Mount{mount}, Env: []string{"EXTRA=x"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(inner.spec.Mounts, tt.mounts) {
+				t.Errorf("mounts %+v, want %+v", inner.spec.Mounts, tt.mounts)
+			}
+			env := []string{"EXTRA=x", "GOFLAGS=-mod=mod", tt.proxy, "GONOSUMDB=*", "GONOPROXY=", "GOPRIVATE="}
+			if !reflect.DeepEqual(inner.spec.Env, env) {
+				t.Errorf("env %q, want %q", inner.spec.Env, env)
+			}
+		})
+	}
+}
+
+func TestUseModuleProxy(t *testing.T) {
+	defer func(c nt.TargetRepoConfig, r Runner) { TargetConfig, ActiveRunner = c, r }(TargetConfig, ActiveRunner)
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(pwd)
+
+	tests := []struct {
+		name    string
+		offline bool
+		proxy   bool
+		// the runner is already wrapped
+		wrapped bool
+		want    bool
+		err     bool
+	}{
+		{name: "online", proxy: true},
+		{name: "offline", offline: true, proxy: true, want: true},
+		{name: "offline without a proxy", offline: true, err: true},
+		{name: "wrapped once", offline: true, proxy: true, wrapped: true, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+			proxy := filepath.Join(dir, "fuzzing_directory", "fake", module_proxy_dir)
+			if tt.proxy {
+				if err := os.MkdirAll(proxy, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			TargetConfig = nt.TargetRepoConfig{TargetRepo: "fake", OfflineModules: tt.offline}
+			inner := &spec_runner{name: "docker"}
+			ActiveRunner = inner
+			if tt.wrapped {
+				ActiveRunner = &proxy_runner{Runner: inner, proxy: proxy}
+			}
+
+			err := use_module_proxy()
+			if (err != nil) != tt.err {
+				t.Fatalf("use_module_proxy() = %v, want error %t", err, tt.err)
+			}
+			r, wrapped := ActiveRunner.(*proxy_runner)
+			if wrapped != tt.want {
+				t.Fatalf("runner %T, want a module proxy runner %t", ActiveRunner, tt.want)
+			}
+			if wrapped && (r.Runner != inner || r.proxy != proxy) {
+				t.Errorf("runner wraps %T with proxy %s, want the docker runner with %s", r.Runner, r.proxy, proxy)
+			}
+		})
+	}
+}
+
+func TestCountModuleVersions(t *testing.T) {
+	dir := t.TempDir()
+	write_files(t, dir, map[string]string{
+		"gopkg.in/yaml.v2/@v/list":                          "v2.4.0\n",
+		"gopkg.in/yaml.v2/@v/v2.4.0.info":                   "{}",
+		"gopkg.in/yaml.v2/@v/v2.4.0.mod":                    "module gopkg.in/yaml.v2\n",
+		"gopkg.in/yaml.v2/@v/v2.4.0.zip":                    "",
+		"golang.org/x/tools/@v/v0.2.0.zip":                  "",
+		"golang.org/x/tools/@v/v0.2.0.mod":                  "module golang.org/x/tools\n",
+		"github.com/sanity-io/litter/@v/v1.5.5.zip":         "",
+		"github.com/sanity-io/litter/@v/v1.5.5.ziphash":     "h1:",
+		"golang.org/x/mod/@v/v0.6.0.mod":                    "module golang.org/x/mod\n",
+		"cache/vcs/0d9d4f3d7c0a1/golang.org/x/sys/v.tar.gz": "",
+	})
+	tests := []struct {
+		dir  string
+		want int
+	}{
+		{dir, 3},
+		{filepath.Join(dir, "gopkg.in"), 1},
+		{filepath.Join(dir, "missing"), 0},
+	}
+	for _, tt := range tests {
+		if got := count_module_versions(tt.dir); got != tt.want {
+			t.Errorf("count_module_versions(%s) = %d, want %d", tt.dir, got, tt.want)
+		}
+	}
+}
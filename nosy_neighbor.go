@@ -1,249 +1,397 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
+	"math"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
+	"github.com/infosecual/nosy/src/campaign"
+	"github.com/infosecual/nosy/src/triage"
 	nt "github.com/infosecual/nosy/src/types"
 )
 
 // global config
 var TargetConfig nt.TargetRepoConfig
-var FuzzFunctions []string
-
-// default help menu
-func print_help_menu() {
-	fmt.Println("Please provide an action and a target YAML file")
-	fmt.Println("Actions:")
-	fmt.Println("\t--init\t\t\tinitialize a target environment")
-	fmt.Println("\t--generate-harness\tgenerate fuzz harnesses for the target")
-	fmt.Println("\t--fuzz\t\t\tfuzz the target")
-	fmt.Println("")
-	fmt.Println("Example usage:")
-	fmt.Println("\t# This will download the target repo")
-	fmt.Println("\tgo run . --init example_source.yaml")
-	fmt.Println("")
-	fmt.Println("\t# This will parse the target source and generate")
-	fmt.Println("\t# the fuzz harnesses")
-	fmt.Println("\tgo run . --generate-harness example_source.yaml")
-	fmt.Println("")
-	fmt.Println("\t# This will build the fuzzers and begin fuzzing the target")
-	fmt.Println("\t# in a docker container")
-	fmt.Println("\tgo run . --fuzz example_source.yaml")
-	fmt.Println("")
+var ConfigPath string
+var Harnesses []nt.Harness
+
+// the commit of the target nosy init or update checked out, if known
+var TargetCommit string
+
+// harness_gen_deps returns the commands adding the modules generated
+// harnesses and parse-package import to the target
+func harness_gen_deps() []string {
+	deps := []string{
+		"go get github.com/infosecual/go-fuzz-fill-utils/fuzzer",
+		"go get github.com/trailofbits/go-fuzz-utils",
+		"go get github.com/infosecual/nosy/src/types",
+	}
+	// add any deps for target package harness_generation
+	return append(deps, TargetConfig.HarnessGenDeps...)
 }
 
-func generate_harness_gen_script(output_dir string) {
-	script := ""
-	script += "go get github.com/infosecual/go-fuzz-fill-utils/fuzzer\n"
-	script += "go get github.com/trailofbits/go-fuzz-utils\n"
-	script += "go get github.com/infosecual/nosy/src/types\n"
+// parse_package_files expands to the sources of parse-package in a script.
+// go run is handed the files rather than the directory as parse-package is
+// built in the target's module, and the files exclude its tests as go run
+// refuses _test.go files.
+const parse_package_files = "$(find /src/cmd/parse-package -maxdepth 1 -name '*.go' ! -name '*_test.go')"
 
-	// add any deps for target package harness_generation
-	for _, dep := range TargetConfig.HarnessGenDeps {
+func generate_harness_gen_script(output_dir string) error {
+	// stop at the first failing command so a broken generation is reported
+	script := "set -e\n"
+	for _, dep := range harness_gen_deps() {
 		script += dep
 		script += "\n"
 	}
 
-	script += fmt.Sprintf("%s run /src/cmd/parse-package/*.go\n", TargetConfig.TargetGoVersion)
+	script += fmt.Sprintf("%s run %s /src/config.yaml\n", TargetConfig.TargetGoVersion, parse_package_files)
 
 	// write the script to the targets /src dir
 	f, err := os.Create(fmt.Sprintf("%s/gen_harness.sh", output_dir))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer f.Close()
 	_, err = f.WriteString(script)
-	f.Close()
-
+	return err
 }
 
-func copy_source_parsers_and_configs(target_dir string) {
+func copy_source_parsers_and_configs(target_dir string) error {
 	// get pwd for subsequent commands
 	pwd, err := os.Getwd()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// emit shell script for harness generation
 	src_dir := pwd + "/src"
-	generate_harness_gen_script(src_dir)
+	if err := generate_harness_gen_script(src_dir); err != nil {
+		return err
+	}
 
 	// copy parsing routines to target's container
 	fmt.Println("\nCopying parsing routines and config to target's assets directory")
 	cmd := fmt.Sprintf("cp -r %s %s", src_dir, target_dir)
-	exec_and_print(cmd)
+	if err := run_step("copy source parsers", cmd); err != nil {
+		return err
+	}
 
-	// copy target config file to target's container
-	config_path := os.Args[2]
-	cmd = fmt.Sprintf("cp %s %s", config_path, target_dir+"/src/config.yaml")
-	exec_and_print(cmd)
+	// write the target config, defaults included, to target's container
+	if err := record_step("copy target config", TargetConfig.WriteFile(target_dir+"/src/config.yaml")); err != nil {
+		return fmt.Errorf("copy target config: %w", err)
+	}
+	return nil
 }
 
-func generate_init_script(target_dir string) {
-	fmt.Println("\nGenerating target's initialization script:")
-	fmt.Println()
+// where target_local_path is mounted while the target is cloned
+const local_target_mount = "/target_local"
+
+// generate_clone_script writes the script that clones the target, or copies
+// the local working tree, into the target directory, where its go.mod is
+// read before the target is set up. The commit checked out is written to
+// target_commit, with a -dirty suffix for a working tree with changes.
+func generate_clone_script(target_dir string) error {
 	script := fmt.Sprintf("REPO_URL=\"%s\"\n", TargetConfig.TargetRepoURL)
 	script += fmt.Sprintf("BRANCH=\"%s\"\n", TargetConfig.TargetRepoBranch)
-	script += fmt.Sprintf("REPO_PREFIX=\"%s\"\n", TargetConfig.TargetRepoImportPrefix)
-	script += `rm /go/src/github/* -rf
+	script += fmt.Sprintf("COMMIT=\"%s\"\n", TargetConfig.TargetRepoCommit)
+	if TargetConfig.TargetLocalPath != "" {
+		script += fmt.Sprintf("LOCAL=\"%s\"\n", local_target_mount)
+	}
+	script += `set -e
+# the mounted tree belongs to another user than the one running git
+git_() { git -c safe.directory='*' -c advice.detachedHead=false "$@"; }
+rm -rf /staging/clone /staging/target_commit
+if [ -n "$LOCAL" ] && [ -z "$COMMIT" ]; then
+	# the working tree as it is, uncommitted changes included
+	mkdir -p /staging/clone
+	cp -a $LOCAL/. /staging/clone/
+elif [ -n "$LOCAL" ]; then
+	git_ clone --quiet $LOCAL /staging/clone
+else
+	git_ clone ${BRANCH:+-b $BRANCH} $REPO_URL /staging/clone
+fi
+cd /staging/clone
+if [ -n "$COMMIT" ]; then
+	# a commit no branch or tag reaches has to be fetched by itself
+	if git_ rev-parse -q --verify "$COMMIT^{commit}" > /dev/null; then
+		git_ checkout --detach "$COMMIT"
+	else
+		git_ fetch origin "$COMMIT"
+		git_ checkout --detach FETCH_HEAD
+	fi
+fi
+if git_ rev-parse --verify -q HEAD > /staging/target_commit; then
+	if [ -n "$(git_ status --porcelain)" ]; then
+		echo "$(cat /staging/target_commit)-dirty" > /staging/target_commit
+	fi
+else
+	rm -f /staging/target_commit
+fi
+`
+	return os.WriteFile(filepath.Join(target_dir, "clone_target.sh"), []byte(script), 0o755)
+}
+
+// clone_mounts returns the mounts the clone script runs with
+func clone_mounts(target_dir string) ([]Mount, error) {
+	mounts := []Mount{{HostPath: target_dir, ContainerPath: "/staging"}}
+	if TargetConfig.TargetLocalPath != "" {
+		local, err := filepath.Abs(TargetConfig.TargetLocalPath)
+		if err != nil {
+			return nil, err
+		}
+		mounts = append(mounts, Mount{HostPath: local, ContainerPath: local_target_mount})
+	}
+	return mounts, nil
+}
+
+func generate_init_script(target_dir string) error {
+	fmt.Println("\nGenerating target's initialization script:")
+	fmt.Println()
+	script := fmt.Sprintf("REPO_PREFIX=\"%s\"\n", TargetConfig.TargetRepoImportPrefix)
+	script += `set -e
+rm /go/src/github/* -rf
 mkdir -p /go/src/$REPO_PREFIX/nosy_fuzz_dir
-mkdir /temp
-git clone -b $BRANCH $REPO_URL /temp
-mv /temp/* /go/src/$REPO_PREFIX
+cp -a /staging/clone/. /go/src/$REPO_PREFIX/
+rm -rf /staging/clone
 cd /go/src/$REPO_PREFIX
 go get -t -d ./...
 cp /go /staging -rp
 # this is an interesting way to get around the fact that we cannot add our
 # harness into the initialized target repo because its perms are restrictive
 # to root:root (we make a user and group with your name in the container,
-# chown everything to it)
-groupadd user
-useradd -s /bin/bash -d / -m -g user user
-chown user -R /staging
+# chown everything to it). Local runs already own the files and must not
+# touch the host's users.
+if [ "$NOSY_RUNNER" != "local" ]; then
+	groupadd user
+	useradd -s /bin/bash -d / -m -g user user
+	chown user -R /staging
+fi
 chmod -R u+w /staging
 `
 	fmt.Print(script)
 	fmt.Println()
 	f, err := os.Create(fmt.Sprintf("%s/init_target.sh", target_dir))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer f.Close()
 	_, err = f.WriteString(script)
-	f.Close()
+	return err
 }
 
-func generate_fuzz_scripts(target_dir string, docker_repo_path string) []string {
+func generate_fuzz_scripts(target_dir string, docker_repo_path string) ([]string, error) {
 	fmt.Println("\nGenerating target's fuzzing scripts:")
 	fmt.Println()
 
 	var scripts []string
 	seconds := TargetConfig.TestTimeSeconds
-	for i := 0; i < len(FuzzFunctions)/2; i++ {
+	for _, h := range Harnesses {
 		script := ""
+		// triage reads the commit from the log to tell what a crash was found on
+		if TargetCommit != "" {
+			script += fmt.Sprintf("echo \"nosy: target commit %s\"\n", TargetCommit)
+		}
 		script += fmt.Sprintf("echo \"fixing up GOROOT for fuzzing\"\n")
 		script += fmt.Sprintf("rm -rf go/*\n")
 		script += fmt.Sprintf("cp -rp /go_backup/* /go\n")
 		script += fmt.Sprintf("cd %s\n", docker_repo_path)
-		script += fmt.Sprintf("echo \"Fuzzing function %s for %d seconds\"\n", FuzzFunctions[2*i], seconds)
-		script += fmt.Sprintf("cd %s\n", FuzzFunctions[2*i+1])
-		script += fmt.Sprintf("%s test -fuzz=%s -fuzztime=%ds -test.fuzzcachedir=/cache/\n", TargetConfig.TargetGoVersion, FuzzFunctions[2*i], seconds)
-		script += fmt.Sprintf("if [ -d \"./testdata/fuzz\" ]; then\n")
-		script += fmt.Sprintf("\tmv ./testdata/fuzz/* /results/\n")
-		script += fmt.Sprintf("\trm -rf ./testdata/fuzz/*\n")
-		script += fmt.Sprintf("\techo \"cd %s && go test -run=%s/nosy_fuzz_dir/%s/.\"\n", FuzzFunctions[2*i+1], docker_repo_path, FuzzFunctions[2*i])
+		// the scheduler hands each run its own slice of the campaign budget
+		script += fmt.Sprintf("FUZZ_SECONDS=${FUZZ_SECONDS:-%d}\n", seconds)
+		script += fmt.Sprintf("echo \"Fuzzing function %s for $FUZZ_SECONDS seconds\"\n", h.Name)
+		script += fmt.Sprintf("cd %s\n", h.Directory)
+		// corpora are kept by harness key, wrapper names repeat across packages
+		key := h.Key()
+		script += fmt.Sprintf("%s test -run=^%s$ -fuzz=^%s$ -fuzztime=${FUZZ_SECONDS}s -test.fuzzcachedir=/cache/%s\n", TargetConfig.TargetGoVersion, h.Name, h.Name, key)
+		script += fmt.Sprintf("if [ -d \"./testdata/fuzz/%s\" ]; then\n", h.Name)
+		// merge rather than move so inputs from earlier runs are kept
+		script += fmt.Sprintf("\tmkdir -p /results/%s\n", key)
+		script += fmt.Sprintf("\tcp -r ./testdata/fuzz/%s/. /results/%s/\n", h.Name, key)
+		script += fmt.Sprintf("\tfor input in ./testdata/fuzz/%s/*; do\n", h.Name)
+		script += fmt.Sprintf("\t\t[ -e \"$input\" ] || continue\n")
+		script += fmt.Sprintf("\t\techo \"replay with: nosy repro %s $(basename $input)\"\n", ConfigPath)
+		script += fmt.Sprintf("\tdone\n")
+		script += fmt.Sprintf("\trm -rf ./testdata/fuzz/%s\n", h.Name)
 		script += fmt.Sprintf("fi\n")
-		filename := fmt.Sprintf("%s/fuzz_%s.sh", target_dir, FuzzFunctions[2*i])
-		docker_relative_filename := fmt.Sprintf("fuzz_%s.sh", FuzzFunctions[2*i])
+		filename := fmt.Sprintf("%s/fuzz_%s.sh", target_dir, key)
+		docker_relative_filename := fmt.Sprintf("fuzz_%s.sh", key)
 		scripts = append(scripts, docker_relative_filename)
 		f, err := os.Create(filename)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		_, err = f.WriteString(script)
 		f.Close()
+		if err != nil {
+			return nil, err
+		}
 	}
-	return scripts
-}
-
-func generate_fuzz_script(target_dir string, docker_repo_path string) {
-	fmt.Println("\nGenerating target's fuzzing script:")
-	fmt.Println()
-
-	script := ""
-	seconds := TargetConfig.TestTimeSeconds
-	for i := 0; i < len(FuzzFunctions)/2; i++ {
-		script += fmt.Sprintf("echo \"Fuzzing function %s for %d seconds\"\n", FuzzFunctions[2*i], seconds)
-		script += fmt.Sprintf("cd %s\n", FuzzFunctions[2*i+1])
-		script += fmt.Sprintf("%s test -fuzz=%s -fuzztime=%ds\n", TargetConfig.TargetGoVersion, FuzzFunctions[2*i], seconds)
-		script += fmt.Sprintf("if [ -d \"./testdata/fuzz\" ]; then\n")
-		script += fmt.Sprintf("\tmv ./testdata/fuzz/* %s/nosy_fuzz_dir/\n", docker_repo_path)
-		script += fmt.Sprintf("\trm -rf ./testdata/fuzz/*\n")
-		script += fmt.Sprintf("\techo \"cd %s && go test -run=%s/nosy_fuzz_dir/%s/.\"\n", FuzzFunctions[2*i+1], docker_repo_path, FuzzFunctions[2*i])
-		script += fmt.Sprintf("fi\n")
-	}
-	f, err := os.Create(fmt.Sprintf("%s/nosy_fuzz_dir/fuzz_target.sh", target_dir))
-	if err != nil {
-		log.Fatal(err)
-	}
-	_, err = f.WriteString(script)
-	f.Close()
+	return scripts, nil
 }
 
-func exec_and_print(command string) {
-	fmt.Println(command)
-	cmd := exec.Command("bash", "-c", command)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Run()
-}
-
-// --init will download and initialize the target repo to fuzz
-func init_target() {
+// init will download and initialize the target repo to fuzz
+func init_target(ctx context.Context, build_image bool) error {
 	fmt.Println("Initializing target repo...")
 	fmt.Println("\tName: ", TargetConfig.TargetRepo)
-	fmt.Println("\tURL: ", TargetConfig.TargetRepoURL)
-	fmt.Println("\tBranch: ", TargetConfig.TargetRepoBranch)
+	if TargetConfig.TargetLocalPath != "" {
+		fmt.Println("\tLocal path: ", TargetConfig.TargetLocalPath)
+	} else {
+		fmt.Println("\tURL: ", TargetConfig.TargetRepoURL)
+		fmt.Println("\tBranch: ", TargetConfig.TargetRepoBranch)
+	}
+	if TargetConfig.TargetRepoCommit != "" {
+		fmt.Println("\tCommit: ", TargetConfig.TargetRepoCommit)
+	}
 	fmt.Println()
-	fmt.Println("Creating docker container for target...")
+	fmt.Printf("Creating %s environment for target...\n", ActiveRunner.Name())
 	fmt.Println()
 
-	// build docker image
-	command := fmt.Sprintf("BUILDKIT=1 docker build -t nosy-neighbor -f nosy-fuzzer.Dockerfile .")
-	exec_and_print(command)
+	// build the image the target's scripts run in
+	var command string
+	if build_image {
+		if err := record_step("build image", ActiveRunner.BuildImage("nosy-fuzzer.Dockerfile")); err != nil {
+			return fmt.Errorf("build image: %w", err)
+		}
+	}
 
 	// get pwd for subsequent commands
 	pwd, err := os.Getwd()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// target directory is where all shared files, scripts, test corpora
 	// (all assets) for the fuzzing container will live
 	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
 
-	// docker will write files as root so we should die here if the file exists
-	// as we will get permission errors
+	// a previous target directory is removed so init always starts over
 	if _, err := os.Stat(target_dir); !os.IsNotExist(err) {
 		fmt.Println("Target directory already exists, removing it...")
-		//fmt.Printf("Please run:\n\tsudo rm -rf %s\n", target_dir)
-		//log.Fatal("Please remove the previous directory before initializing a new target.")
 		command = fmt.Sprintf("rm -rf %s", target_dir)
-		exec_and_print(command)
+		if err := run_step("remove previous target directory", command); err != nil {
+			return err
+		}
 	}
 
 	// create the target's asset directory
 	fmt.Printf("\nCreating target asset directory @ %s\n", target_dir)
 	fmt.Println()
 	command = fmt.Sprintf("mkdir -p %s", target_dir)
-	exec_and_print(command)
+	if err := run_step("create target directory", command); err != nil {
+		return err
+	}
+
+	// clone the target first, its go.mod tells where it goes in the
+	// target's GOPATH
+	if err := generate_clone_script(target_dir); err != nil {
+		return err
+	}
+	mounts, err := clone_mounts(target_dir)
+	if err != nil {
+		return err
+	}
+	clone := RunSpec{
+		Name:   "clone_target.sh",
+		Script: "/staging/clone_target.sh",
+		Mounts: mounts,
+	}
+	if err := record_step(clone.Name, ActiveRunner.Run(ctx, clone)); err != nil {
+		return fmt.Errorf("%s: %w", clone.Name, err)
+	}
+	if err := infer_target_module(target_dir, filepath.Join(target_dir, "clone")); err != nil {
+		return record_step("read target go.mod", err)
+	}
 
 	// generate and populate target's init script in the targets asset folder
-	generate_init_script(target_dir)
+	if err := generate_init_script(target_dir); err != nil {
+		return err
+	}
 
 	// run a the initialization scripts in the target container
 	fmt.Println("\nRunning initialization scripts in target container...")
 	fmt.Println()
-	command = fmt.Sprintf("docker run -v %s:/staging nosy-neighbor /staging/init_target.sh", target_dir)
-	exec_and_print(command)
+	spec := RunSpec{
+		Name:   "init_target.sh",
+		Script: "/staging/init_target.sh",
+		Mounts: []Mount{{HostPath: target_dir, ContainerPath: "/staging"}},
+	}
+	if err := record_step(spec.Name, ActiveRunner.Run(ctx, spec)); err != nil {
+		return fmt.Errorf("%s: %w", spec.Name, err)
+	}
+
+	// the later stages of an offline target only use the modules fetched now
+	if TargetConfig.OfflineModules {
+		if err := fetch_modules(ctx, target_dir); err != nil {
+			return err
+		}
+		return use_module_proxy()
+	}
+	return nil
+}
+
+// infer_target_module reads the module of the freshly cloned target, fills
+// in the import prefix and module declaration the config leaves out and
+// saves the module for later commands
+func infer_target_module(target_dir string, repo_dir string) error {
+	module, err := nt.InferTargetModule(repo_dir)
+	if err != nil {
+		return err
+	}
+	// the config may hold the values of a previous init, start over from
+	// what the YAML says
+	config, err := nt.LoadTargetConfig(ConfigPath)
+	if err != nil {
+		return err
+	}
+	TargetConfig.TargetRepoImportPrefix = config.TargetRepoImportPrefix
+	TargetConfig.TargetModuleDeclaration = config.TargetModuleDeclaration
+	for _, warning := range module.Resolve(&TargetConfig) {
+		fmt.Fprintf(os.Stderr, "nosy: warning: %s: %s\n", ConfigPath, warning)
+	}
+	fmt.Println("\tModule: ", module.ModulePath)
+	if module.GoVersion != "" {
+		fmt.Println("\tGo: ", module.GoVersion)
+	}
+	for _, m := range module.Modules {
+		fmt.Println("\tWorkspace module: ", m)
+	}
+	fmt.Println("\tImport prefix: ", TargetConfig.TargetRepoImportPrefix)
+	commit_file := filepath.Join(target_dir, "target_commit")
+	if commit, err := os.ReadFile(commit_file); err == nil {
+		module.Commit = strings.TrimSpace(string(commit))
+		TargetCommit = module.Commit
+		fmt.Println("\tCommit: ", module.Commit)
+		os.Remove(commit_file)
+	}
+	return module.Save(filepath.Join(target_dir, nt.TargetModuleFile))
 }
 
-func generate_fuzz_harnesses() {
+func generate_fuzz_harnesses(ctx context.Context) error {
 	// get pwd for subsequent commands
 	pwd, err := os.Getwd()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// target directory is where all shared files, scripts, corpora,
 	// container (all assets) for the fuzzing container will live
 	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
 
+	// the target must have been initialized before harnesses can be generated
+	if _, err := os.Stat(target_dir + "/go"); err != nil {
+		return fmt.Errorf("target %s has not been initialized, run \"nosy init\" first", TargetConfig.TargetRepo)
+	}
+
 	// copy source parsing routines into docker image
-	copy_source_parsers_and_configs(target_dir)
+	if err := copy_source_parsers_and_configs(target_dir); err != nil {
+		return err
+	}
 
 	// this is the to $GOROOT in the docker container
 	local_goroot_path := fmt.Sprintf("%s/fuzzing_directory/%s/go",
@@ -262,18 +410,167 @@ func generate_fuzz_harnesses() {
 	fmt.Println()
 	fmt.Println("Source parsing dependencies have been added to the targets asset directory.")
 	fmt.Println("Running harness generation...")
-	command := fmt.Sprintf("docker run --workdir %s/ -v %s:/go -v %s:/src nosy-neighbor /src/gen_harness.sh\n",
-		docker_repo_path, local_goroot_path, local_src_path)
+	spec := RunSpec{
+		Name:    "gen_harness.sh",
+		Script:  "/src/gen_harness.sh",
+		Workdir: docker_repo_path + "/",
+		Mounts: []Mount{
+			{HostPath: local_goroot_path, ContainerPath: "/go"},
+			{HostPath: local_src_path, ContainerPath: "/src"},
+		},
+	}
 	fmt.Println()
-	exec_and_print(command)
+	if err := record_step(spec.Name, ActiveRunner.Run(ctx, spec)); err != nil {
+		return fmt.Errorf("%s: %w", spec.Name, err)
+	}
+	return print_skip_report(fmt.Sprintf("%s/src/%s", local_goroot_path, TargetConfig.TargetRepoImportPrefix))
+}
+
+// print_skip_report prints how many functions got a harness and why the
+// others did not, and writes the full table next to the JSON skip report
+func print_skip_report(local_repo_path string) error {
+	report_path := filepath.Join(local_repo_path, nt.SkipReportFile)
+	report, err := nt.LoadSkipReport(report_path)
+	if err != nil {
+		return err
+	}
+	table_path := strings.TrimSuffix(report_path, ".json") + ".txt"
+	table, err := os.Create(table_path)
+	if err != nil {
+		return err
+	}
+	report.WriteTable(table)
+	if err := table.Close(); err != nil {
+		return err
+	}
+	fmt.Println()
+	report.WriteTotals(os.Stdout)
+	fmt.Printf("\nSkip report: %s\n", report_path)
+	fmt.Printf("             %s\n", table_path)
+	return nil
+}
+
+// fuzz_options are the knobs of the fuzz stage
+type fuzz_options struct {
+	// number of harnesses fuzzed concurrently
+	jobs int
+	// cores given to each harness, 0 splits the host's cores between jobs
+	cpus float64
+	// continue the previous campaign instead of starting over
+	resume bool
+	// total seconds to share between the harnesses, 0 uses the config's
+	// fuzz_budget_seconds or, if unset, seconds_per_target_function each
+	budget int
+	// listen address of the Prometheus metrics endpoint, empty disables it
+	metrics_addr string
+}
+
+// load_harnesses reads the harnesses of the target's harness manifest into
+// Harnesses. Targets generated before the manifest existed list their
+// harnesses in fuzzable.txt as alternating name and directory lines.
+func load_harnesses(local_repo_path string, local_goroot_path string) error {
+	manifest, err := nt.LoadHarnessManifest(local_repo_path + "/" + nt.HarnessManifestFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		manifest, err = load_fuzzable_txt(local_repo_path + "/fuzzable.txt")
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no harnesses to fuzz, run \"nosy generate\" first: %w", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	// harnesses generated by the local runner are listed by their host
+	// path, scripts always refer to the container's $GOPATH
+	to_container := func(path string) string {
+		if strings.HasPrefix(path, local_goroot_path+"/") {
+			return container_gopath + strings.TrimPrefix(path, local_goroot_path)
+		}
+		return path
+	}
+	Harnesses = nil
+	for _, h := range manifest.Harnesses {
+		h.Directory = to_container(h.Directory)
+		h.File = to_container(h.File)
+		Harnesses = append(Harnesses, h)
+	}
+	if len(Harnesses) == 0 {
+		return fmt.Errorf("no harnesses were generated for %s", TargetConfig.TargetRepo)
+	}
+	return nil
+}
+
+// load_fuzzable_txt reads the name and directory pairs of a fuzzable.txt
+func load_fuzzable_txt(path string) (*nt.HarnessManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Fields(string(data))
+	if len(lines)%2 != 0 {
+		return nil, fmt.Errorf("%s: expected harness name and directory pairs, got %d lines", path, len(lines))
+	}
+	manifest := &nt.HarnessManifest{Version: nt.HarnessManifestVersion, Target: TargetConfig.TargetRepo}
+	keys := map[string]bool{}
+	for i := 0; i < len(lines); i += 2 {
+		h := nt.Harness{Name: lines[i], Directory: lines[i+1]}
+		if keys[h.Key()] {
+			return nil, fmt.Errorf("%s: harness %s of %s is listed twice", path, h.Name, h.Directory)
+		}
+		keys[h.Key()] = true
+		manifest.Harnesses = append(manifest.Harnesses, h)
+	}
+	return manifest, nil
+}
+
+// find_harness returns the harness whose corpus is kept in a directory named
+// key. Targets fuzzed before harnesses had keys name the directory after the
+// harness, which is only found while no other package has a harness of the
+// same name.
+func find_harness(key string) (nt.Harness, bool) {
+	var named []nt.Harness
+	for _, h := range Harnesses {
+		if h.Key() == key {
+			return h, true
+		}
+		if h.Name == key {
+			named = append(named, h)
+		}
+	}
+	if len(named) == 1 {
+		return named[0], true
+	}
+	return nt.Harness{}, false
 }
 
-func fuzz() {
+// print_fuzz_stats prints what go test reported for the last run of every
+// harness in the campaign
+func print_fuzz_stats(state *campaign.State) {
+	fmt.Printf("\n%-40s %-12s %8s %12s %10s %8s %8s %8s\n",
+		"Harness", "Status", "Time", "Execs", "Exec/s", "Corpus", "New", "Workers")
+	state.View(func(s *campaign.State) {
+		for _, h := range s.Harnesses {
+			if h.Stats == nil {
+				fmt.Printf("%-40s %-12s %7.0fs %12s %10s %8s %8s %8s\n",
+					h.Key, h.Status, h.Seconds, "-", "-", "-", "-", "-")
+				continue
+			}
+			fmt.Printf("%-40s %-12s %7.0fs %12d %10.0f %8d %8d %8d\n",
+				h.Key, h.Status, h.Seconds, h.TotalExecs, h.Stats.ExecsPerSec,
+				h.Stats.Corpus, h.Stats.NewInteresting, h.Stats.Workers)
+		}
+	})
+}
+
+// how often the progress of a running harness is saved to the campaign state
+const live_save_interval = 5 * time.Second
+
+func fuzz(ctx context.Context, opts fuzz_options) error {
 
 	// get pwd for subsequent commands
 	pwd, err := os.Getwd()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// get the path that we should place the fuzzers shell script in
@@ -290,8 +587,10 @@ func fuzz() {
 		TargetConfig.TargetRepo)
 
 	// make a directory to hold the fuzzing scripts
-	cmd := fmt.Sprintf("mkdir %s", asset_dir)
-	exec_and_print(cmd)
+	cmd := fmt.Sprintf("mkdir -p %s", asset_dir)
+	if err := run_step("create scripts directory", cmd); err != nil {
+		return err
+	}
 
 	// this is the to $GOROOT in the docker container
 	local_goroot_path := fmt.Sprintf("%s/fuzzing_directory/%s/go",
@@ -308,74 +607,317 @@ func fuzz() {
 		pwd,
 		TargetConfig.TargetRepo)
 
+	// create the shared corpus directories up front so every runner can
+	// mount them
+	for _, dir := range []string{local_cache_path, local_results_path} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
 	// this is the path within the docker container that contains the binded
 	// (mounted) target repo
 	docker_repo_path := fmt.Sprintf("/go/src/%s",
 		TargetConfig.TargetRepoImportPrefix)
 
-	// read in function to fuzz
-	file, err := os.Open(local_repo_path + "/" + "fuzzable.txt")
+	if err := load_harnesses(local_repo_path, local_goroot_path); err != nil {
+		return err
+	}
+
+	scripts, err := generate_fuzz_scripts(asset_dir, docker_repo_path)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	// scan the fuzzable text file to create a list of functions to fuzz
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		FuzzFunctions = append(FuzzFunctions, scanner.Text())
+	// split the host's cores between the concurrent jobs unless told
+	// otherwise
+	if opts.jobs < 1 {
+		opts.jobs = 1
 	}
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+	cpus := opts.cpus
+	if cpus <= 0 && opts.jobs > 1 {
+		cpus = math.Max(1, float64(runtime.NumCPU()/opts.jobs))
 	}
 
-	scripts := generate_fuzz_scripts(asset_dir, docker_repo_path)
+	// the campaign state records the progress of every harness so an
+	// interrupted campaign can be resumed
+	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
+	state_path := target_dir + "/" + campaign.StateFile
+	var state *campaign.State
+	if opts.resume {
+		state, err = campaign.LoadOrNew(state_path, TargetConfig.TargetRepo)
+		if err != nil {
+			return err
+		}
+	} else {
+		state = campaign.New(state_path, TargetConfig.TargetRepo)
+	}
+	err = state.Update(func(s *campaign.State) {
+		s.Jobs = opts.jobs
+		s.SecondsPerHarness = TargetConfig.TestTimeSeconds
+		for _, h := range Harnesses {
+			s.AddHarness(h.Key(), h.Name, h.Directory)
+		}
+	})
+	if err != nil {
+		return err
+	}
 
-	fmt.Println("Fuzzing", len(FuzzFunctions)/2, "functions...")
+	// keep the full output of every harness for triage
+	log_dir := target_dir + "/logs"
+	if err := os.MkdirAll(log_dir, 0o755); err != nil {
+		return err
+	}
+
+	buckets_path := filepath.Join(target_dir, "triage", "buckets.json")
+	var metrics *campaign_metrics
+	if opts.metrics_addr != "" {
+		// crashes are bucketed against the known buckets so the bucket IDs
+		// match the triage reports, the buckets file itself is left to triage
+		buckets, err := triage.LoadBuckets(buckets_path, triage.DefaultFrames)
+		if err != nil {
+			return err
+		}
+		metrics = new_campaign_metrics(state, buckets)
+		stop, err := serve_metrics(opts.metrics_addr, metrics)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
 
 	// Iterate through target functions, fix the GOROOT, fuzz the function,
 	// save the offending test cases
-	for _, script := range scripts {
+	script_of := map[string]string{}
+	for i, script := range scripts {
+		script_of[Harnesses[i].Key()] = script
+	}
+	new_job := func(harness string, seconds int) *fuzz_job {
+		job := &fuzz_job{harness: harness, spec: RunSpec{
+			Name:    script_of[harness],
+			Script:  script_of[harness],
+			Workdir: "/scripts",
+			Mounts: []Mount{
+				{HostPath: asset_dir, ContainerPath: "/scripts"},
+				{HostPath: local_goroot_path, ContainerPath: "/go_backup"},
+				{HostPath: local_cache_path, ContainerPath: "/cache"},
+				{HostPath: local_results_path, ContainerPath: "/results"},
+			},
+			CPUs: cpus,
+		}}
+		if seconds > 0 {
+			job.spec.Env = []string{fmt.Sprintf("FUZZ_SECONDS=%d", seconds)}
+		}
+		return job
+	}
+	hooks := job_hooks{
+		start: func(job *fuzz_job) {
+			if err := state.Start(job.harness); err != nil {
+				fmt.Fprintln(os.Stderr, "nosy: warning: failed to save campaign state:", err)
+			}
+		},
+		line: func(job *fuzz_job, line string) {
+			// keep the state current for nosy status --watch without
+			// rewriting it for every progress line
+			if job.live.Add(line) && time.Since(job.live_saved) >= live_save_interval {
+				job.live_saved = time.Now()
+				if err := state.RecordLive(job.harness, job.live); err != nil {
+					fmt.Fprintln(os.Stderr, "nosy: warning: failed to save campaign state:", err)
+				}
+			}
+		},
+		finish: func(job *fuzz_job) {
+			// interrupted or broken runs are retried on resume
+			status := campaign.StatusFromOutput(job.output.String())
+			if job.err != nil && status == campaign.Done {
+				status = campaign.Pending
+			}
+			if err := state.Finish(job.harness, status, job.elapsed, job.err); err != nil {
+				fmt.Fprintln(os.Stderr, "nosy: warning: failed to save campaign state:", err)
+			}
+			if err := state.RecordRun(job.harness, job.live); err != nil {
+				fmt.Fprintln(os.Stderr, "nosy: warning: failed to save campaign state:", err)
+			}
+			// the dashboard reads the crashes from the state, not the logs
+			crashes, err := summarize_crashes(buckets_path, job.output.Bytes())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "nosy: warning: failed to bucket crashes:", err)
+			}
+			if err := state.RecordCrashes(job.harness, crashes); err != nil {
+				fmt.Fprintln(os.Stderr, "nosy: warning: failed to save campaign state:", err)
+			}
+			if metrics != nil {
+				metrics.add_output(job.output.Bytes())
+			}
+			log_path := fmt.Sprintf("%s/%s.log", log_dir, job.harness)
+			if err := os.WriteFile(log_path, job.output.Bytes(), 0o644); err != nil {
+				fmt.Fprintln(os.Stderr, "nosy: warning: failed to save fuzzing log:", err)
+			}
+		},
+	}
+
+	// A failed run of one harness does not stop the campaign, it is reported
+	// once every harness had its turn
+	failed, ran := 0, 0
+	report := func(jobs []*fuzz_job) int {
+		round_failed := 0
+		for _, job := range jobs {
+			if errors.Is(job.err, context.Canceled) {
+				continue
+			}
+			if record_step(job.spec.Name, job.err) != nil {
+				fmt.Fprintf(os.Stderr, "nosy: fuzzing %s failed: %v\n", job.spec.Name, job.err)
+				round_failed++
+			}
+		}
+		failed += round_failed
+		ran += len(jobs)
+		return round_failed
+	}
 
-		// prune docker containers tagged as nosy. This will prune all stopped containers!!!
-		cmd = "docker container prune -f"
-		exec_and_print(cmd)
+	budget := opts.budget
+	if budget <= 0 {
+		budget = TargetConfig.FuzzBudgetSeconds
+	}
+	if budget > 0 {
+		// hand the budget out in rounds, each round only starts once the
+		// previous one finished so it can tell which harnesses still grow
+		if err := state.Update(func(s *campaign.State) { s.SetBudget(budget) }); err != nil {
+			return err
+		}
+		for ctx.Err() == nil {
+			slices, err := state.NextRound()
+			if err != nil {
+				return err
+			}
+			var remaining, round int
+			state.View(func(s *campaign.State) {
+				remaining, round = s.Budget.Remaining(), s.Budget.Round
+			})
+			if len(slices) == 0 {
+				fmt.Printf("Fuzzing budget of %ds done, %ds left unused\n", budget, remaining)
+				break
+			}
+			var jobs []*fuzz_job
+			for _, slice := range slices {
+				jobs = append(jobs, new_job(slice.Harness, slice.Seconds))
+			}
+			fmt.Printf("Round %d: fuzzing %d functions with %d concurrent jobs, %ds of the budget still unallocated...\n",
+				round, len(jobs), opts.jobs, remaining)
+			run_fuzz_jobs(ctx, jobs, opts.jobs, hooks)
+			// failed runs get their slice back, stop rather than retry them
+			// forever, resuming the campaign picks them up again
+			if report(jobs) > 0 {
+				break
+			}
+		}
+	} else {
+		var jobs []*fuzz_job
+		skipped := 0
+		for _, h := range Harnesses {
+			harness := h.Key()
+			finished := false
+			state.View(func(s *campaign.State) {
+				finished = s.Harness(harness).Status.Finished()
+			})
+			if finished {
+				skipped++
+				continue
+			}
+			jobs = append(jobs, new_job(harness, 0))
+		}
+
+		if skipped > 0 {
+			fmt.Printf("Resuming campaign, skipping %d finished harnesses\n", skipped)
+		}
+		fmt.Println("Fuzzing", len(jobs), "functions with", opts.jobs, "concurrent jobs...")
+		run_fuzz_jobs(ctx, jobs, opts.jobs, hooks)
+		report(jobs)
+	}
+
+	// triage everything the campaign found so far
+	if err := record_step("triage", triage_target(target_dir)); err != nil {
+		return fmt.Errorf("triage: %w", err)
+	}
+	print_fuzz_stats(state)
 
-		//run the fuzz script
-		cmd = fmt.Sprintf("docker run --workdir /scripts -v %s:/scripts -v %s:/go_backup -v %s:/cache -v %s:/results nosy-neighbor %s\n",
-			asset_dir, local_goroot_path, local_cache_path, local_results_path, script)
-		exec_and_print(cmd)
+	if ctx.Err() != nil {
+		return fmt.Errorf("fuzzing interrupted: %w", ctx.Err())
 	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fuzz runs failed", failed, ran)
+	}
+	return nil
+}
 
+// count_entries returns the number of entries in a directory, or 0 if the
+// directory does not exist
+func count_entries(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	return len(entries)
 }
 
-func main() {
-	// print help menu if invalid arguments
-	if len(os.Args[1:]) < 2 {
-		print_help_menu()
-		return
+// status prints how far the target has progressed through the nosy pipeline
+func status() error {
+	// get pwd for subsequent commands
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
 	}
 
-	// verify YAML config file format
-	if strings.HasSuffix(os.Args[2], ".yaml") {
-		TargetConfig = nt.ParseTargetConfig(os.Args[2])
-	} else {
-		fmt.Println("The target config must be a YAML file (.yaml)")
-		return
-	}
-
-	// init subcommand
-	if os.Args[1] == "--init" {
-		init_target()
-		return
-	} else if os.Args[1] == "--generate-harness" {
-		generate_fuzz_harnesses()
-		return
-	} else if os.Args[1] == "--fuzz" {
-		fuzz()
-		//fmt.Println("unimplemented")
-		return
+	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
+	local_repo_path := fmt.Sprintf("%s/go/src/%s", target_dir, TargetConfig.TargetRepoImportPrefix)
+
+	fmt.Println("Target:", TargetConfig.TargetRepo)
+	if TargetConfig.TargetLocalPath != "" {
+		fmt.Println("\tLocal path:\t", TargetConfig.TargetLocalPath)
 	} else {
-		fmt.Println("unknown action: ", os.Args[1])
-		return
+		fmt.Println("\tURL:\t\t", TargetConfig.TargetRepoURL)
+		fmt.Println("\tBranch:\t\t", TargetConfig.TargetRepoBranch)
+	}
+	fmt.Println("\tDirectory:\t", target_dir)
+
+	if _, err := os.Stat(local_repo_path); err != nil {
+		fmt.Println("\tInitialized:\t no")
+		return nil
+	}
+	fmt.Println("\tInitialized:\t yes")
+	fmt.Println("\tModule:\t\t", TargetConfig.TargetModuleDeclaration)
+	if module, err := nt.LoadTargetModule(filepath.Join(target_dir, nt.TargetModuleFile)); err == nil {
+		if module.GoVersion != "" {
+			fmt.Println("\tGo directive:\t", module.GoVersion)
+		}
+		if module.Commit != "" {
+			fmt.Println("\tCommit:\t\t", module.Commit)
+		}
+	}
+	if TargetConfig.OfflineModules {
+		fmt.Println("\tModule proxy:\t", count_module_versions(filepath.Join(target_dir, module_proxy_dir)), "module versions")
+	}
+
+	harnesses := 0
+	if err := load_harnesses(local_repo_path, target_dir+"/go"); err == nil {
+		harnesses = len(Harnesses)
+	}
+	fmt.Println("\tHarnesses:\t", harnesses)
+	fmt.Println("\tFuzz scripts:\t", count_entries(target_dir+"/scripts"))
+
+	state, err := campaign.Load(target_dir + "/" + campaign.StateFile)
+	if err == nil {
+		counts := state.Counts()
+		fmt.Println("\tCampaign:\t", len(state.Harnesses), "harnesses")
+		for _, s := range []campaign.Status{campaign.Pending, campaign.Running, campaign.Done, campaign.Crashed, campaign.BuildFailed} {
+			fmt.Printf("\t\t%-12s %d\n", s, counts[s])
+		}
+		if b := state.Budget; b != nil {
+			fmt.Printf("\tBudget:\t\t %ds of %ds spent in %d rounds\n", b.SpentSeconds, b.TotalSeconds, b.Round)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
 	}
+	fmt.Println("\tResults:\t", count_entries(target_dir+"/results"))
+	return nil
 }
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	nt "github.com/infosecual/nosy/src/types"
+)
+
+func TestLoadFuzzableTxt(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  string
+		want []nt.Harness
+		err  string
+	}{
+		{
+			name: "pairs",
+			txt:  "Fuzz_Nosy_Sum__\n/go/src/example.com/fake/parse\nFuzz_Nosy_Sum__\n/go/src/example.com/fake/other\n",
+			want: []nt.Harness{
+				{Name: "Fuzz_Nosy_Sum__", Directory: "/go/src/example.com/fake/parse"},
+				{Name: "Fuzz_Nosy_Sum__", Directory: "/go/src/example.com/fake/other"},
+			},
+		},
+		{name: "empty", txt: ""},
+		{name: "odd lines", txt: "Fuzz_Nosy_Sum__\n/go/src/example.com/fake/parse\nFuzz_Nosy_Header__\n", err: "got 3 lines"},
+		{name: "listed twice", txt: "Fuzz_Nosy_Sum__\n/go/src/example.com/fake/parse\nFuzz_Nosy_Sum__\n/go/src/example.com/fake/parse\n", err: "is listed twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fuzzable.txt")
+			if err := os.WriteFile(path, []byte(tt.txt), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			m, err := load_fuzzable_txt(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("load_fuzzable_txt() = %v, want an error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Version != nt.HarnessManifestVersion || len(m.Harnesses) != len(tt.want) {
+				t.Fatalf("load_fuzzable_txt() = %+v, want %+v", m, tt.want)
+			}
+			for i, h := range m.Harnesses {
+				if h != tt.want[i] {
+					t.Errorf("harness %d = %+v, want %+v", i, h, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadHarnesses(t *testing.T) {
+	defer func(h []nt.Harness) { Harnesses = h }(Harnesses)
+	const goroot = "/tmp/nosy/fake/go"
+	manifest := &nt.HarnessManifest{
+		Version:     nt.HarnessManifestVersion,
+		Target:      "fake",
+		GeneratedAt: time.Now(),
+		Harnesses: []nt.Harness{
+			// generated by the docker runner
+			{Name: "Fuzz_Nosy_Sum__", Package: "example.com/fake/parse", Directory: "/go/src/example.com/fake/parse", File: "/go/src/example.com/fake/parse/Fuzz_Nosy_test.go"},
+			// generated by the local runner
+			{Name: "Fuzz_Nosy_Sum__", Package: "example.com/fake/other", Directory: goroot + "/src/example.com/fake/other", File: goroot + "/src/example.com/fake/other/Fuzz_Nosy_test.go"},
+		},
+	}
+	fuzzable := "Fuzz_Nosy_Header__\n/go/src/example.com/fake/other\n"
+
+	tests := []struct {
+		name     string
+		manifest bool
+		fuzzable bool
+		want     []string
+		err      string
+	}{
+		{name: "manifest", manifest: true, want: []string{"/go/src/example.com/fake/parse", "/go/src/example.com/fake/other"}},
+		{name: "manifest over fuzzable.txt", manifest: true, fuzzable: true, want: []string{"/go/src/example.com/fake/parse", "/go/src/example.com/fake/other"}},
+		{name: "fuzzable.txt", fuzzable: true, want: []string{"/go/src/example.com/fake/other"}},
+		{name: "nothing generated", err: "run \"nosy generate\" first"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			if tt.manifest {
+				if err := manifest.Save(filepath.Join(repo, nt.HarnessManifestFile)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.fuzzable {
+				if err := os.WriteFile(filepath.Join(repo, "fuzzable.txt"), []byte(fuzzable), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := load_harnesses(repo, goroot)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) || !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("load_harnesses() = %v, want an error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var dirs []string
+			for _, h := range Harnesses {
+				dirs = append(dirs, h.Directory)
+				if h.File != "" && !strings.HasPrefix(h.File, h.Directory+"/") {
+					t.Errorf("harness %s file %s is outside %s", h.Name, h.File, h.Directory)
+				}
+			}
+			if strings.Join(dirs, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("harness directories %q, want %q", dirs, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindHarness(t *testing.T) {
+	defer func(h []nt.Harness) { Harnesses = h }(Harnesses)
+	sum_parse := nt.Harness{Name: "Fuzz_Nosy_Sum__", Package: "example.com/fake/parse", Directory: "/go/src/example.com/fake/parse"}
+	sum_other := nt.Harness{Name: "Fuzz_Nosy_Sum__", Package: "example.com/fake/other", Directory: "/go/src/example.com/fake/other"}
+	header := nt.Harness{Name: "Fuzz_Nosy_Header__", Package: "example.com/fake/other", Directory: "/go/src/example.com/fake/other"}
+	Harnesses = []nt.Harness{sum_parse, sum_other, header}
+
+	tests := []struct {
+		key   string
+		want  nt.Harness
+		found bool
+	}{
+		{sum_parse.Key(), sum_parse, true},
+		{sum_other.Key(), sum_other, true},
+		// corpus directories named before harnesses had keys
+		{"Fuzz_Nosy_Header__", header, true},
+		{"Fuzz_Nosy_Sum__", nt.Harness{}, false},
+		{"Fuzz_Nosy_Div__", nt.Harness{}, false},
+	}
+	for _, tt := range tests {
+		got, found := find_harness(tt.key)
+		if got != tt.want || found != tt.found {
+			t.Errorf("find_harness(%q) = %+v, %t, want %+v, %t", tt.key, got, found, tt.want, tt.found)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/infosecual/nosy/src/campaign"
+)
+
+// fuzz_job is one harness run scheduled by run_fuzz_jobs
+type fuzz_job struct {
+	// key of the harness, see nt.Harness.Key
+	harness string
+	spec    RunSpec
+	// set once the job has run
+	err     error
+	output  bytes.Buffer
+	elapsed time.Duration
+	// progress reported by go test, updated while the job runs, and when
+	// it was last saved to the campaign state
+	live       campaign.Run
+	live_saved time.Time
+}
+
+// job_hooks are called by the worker running a job, right before it starts,
+// for every line of output while it runs and once it is done
+type job_hooks struct {
+	start  func(job *fuzz_job)
+	line   func(job *fuzz_job, line string)
+	finish func(job *fuzz_job)
+}
+
+// line_writer calls fn with every complete line written to it
+type line_writer struct {
+	partial []byte
+	fn      func(line string)
+}
+
+func (l *line_writer) Write(p []byte) (int, error) {
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.fn(string(l.partial[:i]))
+		l.partial = l.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// ordered_output buffers the output of concurrent jobs and prints each job's
+// output in one piece, in the order the jobs were queued
+type ordered_output struct {
+	mu      sync.Mutex
+	out     io.Writer
+	buffers []*bytes.Buffer
+	done    []bool
+	next    int
+}
+
+func new_ordered_output(out io.Writer, n int) *ordered_output {
+	o := &ordered_output{out: out, buffers: make([]*bytes.Buffer, n), done: make([]bool, n)}
+	for i := range o.buffers {
+		o.buffers[i] = new(bytes.Buffer)
+	}
+	return o
+}
+
+// writer returns the writer for job i, safe for use as both stdout and stderr
+func (o *ordered_output) writer(i int) io.Writer {
+	return &locked_writer{mu: &o.mu, w: o.buffers[i]}
+}
+
+// finish marks job i as complete and prints every completed job that is not
+// waiting on an earlier one
+func (o *ordered_output) finish(i int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.done[i] = true
+	for o.next < len(o.done) && o.done[o.next] {
+		o.out.Write(o.buffers[o.next].Bytes())
+		o.buffers[o.next] = nil
+		o.next++
+	}
+}
+
+type locked_writer struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *locked_writer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// run_fuzz_jobs runs the jobs on a pool of n workers. With a single worker
+// output is streamed as it happens, otherwise each job's output is printed
+// once it finished, in queue order. Cancelling ctx stops queued jobs from
+// starting and kills the running ones.
+func run_fuzz_jobs(ctx context.Context, jobs []*fuzz_job, n int, hooks job_hooks) {
+	if n < 1 {
+		n = 1
+	}
+	var output *ordered_output
+	if n > 1 {
+		output = new_ordered_output(os.Stdout, len(jobs))
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				job := jobs[i]
+				// the queue may still hand out a job once cancelled
+				if ctx.Err() != nil {
+					job.err = ctx.Err()
+					if output != nil {
+						output.finish(i)
+					}
+					continue
+				}
+				// keep a copy of the output for the finish hook
+				var terminal io.Writer = os.Stdout
+				if output != nil {
+					terminal = output.writer(i)
+					fmt.Fprintf(terminal, "\n==> %s\n", job.spec.Name)
+				}
+				writers := multi_writer{terminal, &job.output}
+				if hooks.line != nil {
+					writers = append(writers, &line_writer{fn: func(line string) { hooks.line(job, line) }})
+				}
+				job.spec.Stdout = &locked_writer{mu: new(sync.Mutex), w: writers}
+				job.spec.Stderr = job.spec.Stdout
+
+				if hooks.start != nil {
+					hooks.start(job)
+				}
+				start := time.Now()
+				job.err = ActiveRunner.Run(ctx, job.spec)
+				job.elapsed = time.Since(start)
+				if hooks.finish != nil {
+					hooks.finish(job)
+				}
+				if output != nil {
+					output.finish(i)
+				}
+			}
+		}()
+	}
+
+	for i := range jobs {
+		select {
+		case queue <- i:
+			continue
+		case <-ctx.Done():
+		}
+		// interrupted, the remaining jobs never ran
+		for j := i; j < len(jobs); j++ {
+			jobs[j].err = ctx.Err()
+			if output != nil {
+				output.finish(j)
+			}
+		}
+		break
+	}
+	close(queue)
+	wg.Wait()
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLineWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{"one line", []string{"fuzz: elapsed: 3s\n"}, []string{"fuzz: elapsed: 3s"}},
+		{"split line", []string{"fuzz: ela", "psed: 3s\nok"}, []string{"fuzz: elapsed: 3s"}},
+		{"several lines", []string{"a\nb\n\nc\n"}, []string{"a", "b", "", "c"}},
+		{"no newline", []string{"a", "b"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			w := &line_writer{fn: func(line string) { lines = append(lines, line) }}
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", s, n, err)
+				}
+			}
+			if !reflect.DeepEqual(lines, tt.want) {
+				t.Errorf("lines %q, want %q", lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderedOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		finish []int
+		// output after each finish
+		want []string
+	}{
+		{"in order", []int{0, 1, 2}, []string{"0", "01", "012"}},
+		{"reversed", []int{2, 1, 0}, []string{"", "", "012"}},
+		{"waits on the first", []int{1, 0, 2}, []string{"", "01", "012"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			o := new_ordered_output(&out, 3)
+			for i := 0; i < 3; i++ {
+				fmt.Fprint(o.writer(i), i)
+			}
+			for i, job := range tt.finish {
+				o.finish(job)
+				if out.String() != tt.want[i] {
+					t.Errorf("after finishing job %d output is %q, want %q", job, out.String(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// fake_runner writes a few lines for every run and fails the runs named in
+// fail, later runs finish first
+type fake_runner struct {
+	mu   sync.Mutex
+	ran  []string
+	fail map[string]bool
+}
+
+func (r *fake_runner) Name() string                       { return "fake" }
+func (r *fake_runner) BuildImage(dockerfile string) error { return nil }
+
+func (r *fake_runner) Run(ctx context.Context, spec RunSpec) error {
+	r.mu.Lock()
+	r.ran = append(r.ran, spec.Name)
+	r.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(spec.Stdout, "%s line %d\n", spec.Name, i)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(10-len(spec.Name)) * time.Millisecond):
+		}
+	}
+	if r.fail[spec.Name] {
+		return &CommandError{Command: spec.Name, ExitCode: 1}
+	}
+	return nil
+}
+
+// capture_stdout runs fn and returns what it wrote to os.Stdout
+func capture_stdout(t *testing.T, fn func()) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stdout := os.Stdout
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+	fn()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRunFuzzJobs(t *testing.T) {
+	defer func(r Runner) { ActiveRunner = r }(ActiveRunner)
+	names := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+	tests := []struct {
+		name    string
+		workers int
+	}{
+		{"streamed", 1},
+		{"pool", 3},
+		{"more workers than jobs", 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &fake_runner{fail: map[string]bool{"ccc": true}}
+			ActiveRunner = runner
+			var jobs []*fuzz_job
+			for _, name := range names {
+				jobs = append(jobs, &fuzz_job{harness: name, spec: RunSpec{Name: name}})
+			}
+			var mu sync.Mutex
+			started, finished := map[string]bool{}, map[string]bool{}
+			lines := map[string]int{}
+			hooks := job_hooks{
+				start: func(job *fuzz_job) {
+					mu.Lock()
+					defer mu.Unlock()
+					started[job.harness] = true
+				},
+				line: func(job *fuzz_job, line string) {
+					mu.Lock()
+					defer mu.Unlock()
+					if !strings.HasPrefix(line, job.harness+" line ") {
+						t.Errorf("job %s got line %q", job.harness, line)
+					}
+					lines[job.harness]++
+				},
+				finish: func(job *fuzz_job) {
+					mu.Lock()
+					defer mu.Unlock()
+					finished[job.harness] = true
+				},
+			}
+			out := capture_stdout(t, func() {
+				run_fuzz_jobs(context.Background(), jobs, tt.workers, hooks)
+			})
+
+			for _, job := range jobs {
+				if !started[job.harness] || !finished[job.harness] || lines[job.harness] != 3 {
+					t.Errorf("job %s: started %t, finished %t, %d lines", job.harness, started[job.harness], finished[job.harness], lines[job.harness])
+				}
+				if (job.err != nil) != (job.harness == "ccc") {
+					t.Errorf("job %s: error %v", job.harness, job.err)
+				}
+				if strings.Count(job.output.String(), "\n") != 3 {
+					t.Errorf("job %s kept output %q", job.harness, job.output.String())
+				}
+			}
+
+			// every job's lines follow each other, in queue order
+			var order []string
+			for _, line := range strings.Split(out, "\n") {
+				if line == "" {
+					continue
+				}
+				name := strings.Fields(strings.TrimPrefix(line, "==> "))[0]
+				if len(order) == 0 || order[len(order)-1] != name {
+					order = append(order, name)
+				}
+			}
+			if !reflect.DeepEqual(order, names) {
+				t.Errorf("output in the order %q, want %q:\n%s", order, names, out)
+			}
+		})
+	}
+}
+
+// cancelling the campaign stops queued jobs from starting
+func TestRunFuzzJobsCancelled(t *testing.T) {
+	defer func(r Runner) { ActiveRunner = r }(ActiveRunner)
+	runner := &fake_runner{}
+	ActiveRunner = runner
+	ctx, cancel := context.WithCancel(context.Background())
+	var jobs []*fuzz_job
+	for _, name := range []string{"a", "b", "c", "d"} {
+		jobs = append(jobs, &fuzz_job{harness: name, spec: RunSpec{Name: name}})
+	}
+	hooks := job_hooks{start: func(job *fuzz_job) { cancel() }}
+	capture_stdout(t, func() {
+		run_fuzz_jobs(ctx, jobs, 2, hooks)
+	})
+	if len(runner.ran) > 2 {
+		t.Errorf("%d jobs ran after the campaign was cancelled: %q", len(runner.ran), runner.ran)
+	}
+	for _, job := range jobs {
+		if !errors.Is(job.err, context.Canceled) {
+			t.Errorf("job %s: error %v, want %v", job.harness, job.err, context.Canceled)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package main
+
+import (
+	"os/exec"
+)
+
+// start_process_group is a no-op where process groups are not available
+func start_process_group(cmd *exec.Cmd) {}
+
+// kill_process_group kills cmd, children it started may outlive it
+func kill_process_group(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	cmd.Process.Kill()
+}
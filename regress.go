@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/infosecual/nosy/src/triage"
+)
+
+// regression_crash is one entry of the crash list handed to parse-package's
+// regress mode
+type regression_crash struct {
+	Bucket    string `json:"bucket"`
+	Harness   string `json:"harness"`
+	Directory string `json:"directory"`
+	Input     string `json:"input"`
+	Panic     string `json:"panic"`
+}
+
+// generate_regress_script writes the script that runs parse-package in its
+// regress mode
+func generate_regress_script(output_dir string) error {
+	script := "set -e\n"
+	script += fmt.Sprintf("%s run %s /src/config.yaml regress /src/regress/crashes.json\n", TargetConfig.TargetGoVersion, parse_package_files)
+	return os.WriteFile(filepath.Join(output_dir, "regress.sh"), []byte(script), 0o755)
+}
+
+// regress is the "nosy regress" command, it turns the crashes of the given
+// triage buckets, or of every bucket if none are given, into standalone
+// regression tests in the target's packages
+func regress(ctx context.Context, bucket_ids []string) error {
+	// get pwd for subsequent commands
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
+	local_goroot_path := target_dir + "/go"
+	local_src_path := target_dir + "/src"
+	local_repo_path := fmt.Sprintf("%s/src/%s", local_goroot_path, TargetConfig.TargetRepoImportPrefix)
+	docker_repo_path := fmt.Sprintf("/go/src/%s", TargetConfig.TargetRepoImportPrefix)
+	buckets_path := filepath.Join(target_dir, "triage", "buckets.json")
+
+	if _, err := os.Stat(buckets_path); err != nil {
+		return fmt.Errorf("no crash buckets, run \"nosy fuzz\" first: %w", err)
+	}
+	buckets, err := triage.LoadBuckets(buckets_path, triage.DefaultFrames)
+	if err != nil {
+		return err
+	}
+	if err := load_harnesses(local_repo_path, local_goroot_path); err != nil {
+		return err
+	}
+
+	selected := buckets.Sorted()
+	if len(bucket_ids) > 0 {
+		selected = nil
+		for _, id := range bucket_ids {
+			bucket, ok := buckets.Buckets[id]
+			if !ok {
+				return fmt.Errorf("%w: unknown crash bucket %s", errUsage, id)
+			}
+			selected = append(selected, bucket)
+		}
+	}
+
+	// refresh parse-package so it knows the regress mode
+	if err := copy_source_parsers_and_configs(target_dir); err != nil {
+		return err
+	}
+
+	// the crash inputs are copied next to the crash list, the minimized
+	// input is preferred as it makes for a more readable test
+	regress_dir := filepath.Join(local_src_path, "regress")
+	if err := os.RemoveAll(regress_dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(regress_dir, 0o755); err != nil {
+		return err
+	}
+	var crashes []regression_crash
+	for _, bucket := range selected {
+		input := bucket.Minimized
+		if input == "" {
+			input = bucket.Representative
+		}
+		if input == "" {
+			fmt.Printf("Skipping %s, no input was saved for it\n", bucket.ID)
+			continue
+		}
+		key := filepath.Base(filepath.Dir(input))
+		h, ok := find_harness(key)
+		if !ok {
+			fmt.Printf("Skipping %s, %s is not one of the target's harnesses\n", bucket.ID, key)
+			continue
+		}
+		data, err := os.ReadFile(input)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(regress_dir, bucket.ID), data, 0o644); err != nil {
+			return err
+		}
+		directory := strings.TrimPrefix(strings.TrimPrefix(h.Directory, docker_repo_path), "/")
+		if directory == "" {
+			directory = "."
+		}
+		crashes = append(crashes, regression_crash{
+			Bucket:    bucket.ID,
+			Harness:   h.Name,
+			Directory: directory,
+			Input:     bucket.ID,
+			Panic:     bucket.Panic,
+		})
+	}
+	if len(crashes) == 0 {
+		return fmt.Errorf("no crashes to write regression tests for")
+	}
+	data, err := json.MarshalIndent(crashes, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(regress_dir, "crashes.json"), data, 0o644); err != nil {
+		return err
+	}
+	if err := generate_regress_script(local_src_path); err != nil {
+		return err
+	}
+
+	fmt.Println("Generating regression tests for", len(crashes), "crashes...")
+	spec := RunSpec{
+		Name:    "regress.sh",
+		Script:  "/src/regress.sh",
+		Workdir: docker_repo_path + "/",
+		Mounts: []Mount{
+			{HostPath: local_goroot_path, ContainerPath: "/go"},
+			{HostPath: local_src_path, ContainerPath: "/src"},
+		},
+	}
+	run_err := record_step(spec.Name, ActiveRunner.Run(ctx, spec))
+
+	// record the tests that were written, even if some crashes failed
+	for _, crash := range crashes {
+		test := filepath.Join(local_repo_path, crash.Directory, fmt.Sprintf("Nosy_regression_%s_test.go", crash.Bucket))
+		if _, err := os.Stat(test); err == nil {
+			buckets.Buckets[crash.Bucket].Regression = test
+		}
+	}
+	if err := buckets.Save(buckets_path); err != nil {
+		return err
+	}
+	if run_err != nil {
+		return fmt.Errorf("%s: %w", spec.Name, run_err)
+	}
+	return nil
+}
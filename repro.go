@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/infosecual/nosy/src/triage"
+)
+
+// locate_crash finds the saved input a crash ID or file refers to and
+// returns it with the key of the harness that found it. An ID is either the
+// name go test gave the input, looked up in the results directory, or a
+// triage bucket ID, which resolves to the input nosy minimize made of the
+// bucket's representative, or the representative itself.
+func locate_crash(target_dir string, crash string) (string, string, error) {
+	// saved inputs are always kept as <harness key>/<input-id>
+	if info, err := os.Stat(crash); err == nil && !info.IsDir() {
+		input, err := filepath.Abs(crash)
+		if err != nil {
+			return "", "", err
+		}
+		return filepath.Base(filepath.Dir(input)), input, nil
+	}
+	if strings.ContainsRune(crash, filepath.Separator) {
+		return "", "", fmt.Errorf("no crash input at %s", crash)
+	}
+
+	var matches []string
+	for _, pattern := range []string{
+		filepath.Join(target_dir, "results", "*", crash),
+		filepath.Join(target_dir, "triage", "buckets", crash, "*", "*"),
+	} {
+		found, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", "", err
+		}
+		matches = append(matches, found...)
+		if len(matches) > 0 {
+			break
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return "", "", fmt.Errorf("no saved input or crash bucket %s in %s", crash, target_dir)
+	case len(matches) > 1 && filepath.Base(matches[0]) == crash:
+		return "", "", fmt.Errorf("input %s was saved by more than one harness, pass its path instead:\n\t%s",
+			crash, strings.Join(matches, "\n\t"))
+	}
+	input := matches[0]
+	for _, match := range matches {
+		if strings.HasSuffix(match, ".min") {
+			input = match
+		}
+	}
+	return filepath.Base(filepath.Dir(input)), input, nil
+}
+
+// generate_repro_script writes the script that restores input into the
+// harness's testdata/fuzz corpus and runs only that input
+func generate_repro_script(target_dir string, key string, harness string, harness_dir string, input_id string) (string, error) {
+	script := ""
+	script += fmt.Sprintf("set -e\n")
+	script += fmt.Sprintf("echo \"fixing up GOROOT for the reproduction\"\n")
+	script += fmt.Sprintf("cp -rp /go_backup/. /go\n")
+	script += fmt.Sprintf("cd %s\n", harness_dir)
+	script += fmt.Sprintf("mkdir -p ./testdata/fuzz/%s\n", harness)
+	script += fmt.Sprintf("cp /crash/%s ./testdata/fuzz/%s/%s\n", input_id, harness, input_id)
+	script += fmt.Sprintf("echo \"Replaying %s/%s\"\n", harness, input_id)
+	script += fmt.Sprintf("%s test -run=%s/%s\n", TargetConfig.TargetGoVersion, harness, input_id)
+
+	filename := fmt.Sprintf("repro_%s.sh", key)
+	if err := os.WriteFile(filepath.Join(target_dir, filename), []byte(script), 0o755); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// repro replays a saved crash input against its harness in a fresh
+// environment and prints the panic and stack trace it causes
+func repro(ctx context.Context, crash string) error {
+	// get pwd for subsequent commands
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
+	local_goroot_path := target_dir + "/go"
+	local_repo_path := fmt.Sprintf("%s/src/%s", local_goroot_path, TargetConfig.TargetRepoImportPrefix)
+	asset_dir := target_dir + "/scripts"
+
+	key, input, err := locate_crash(target_dir, crash)
+	if err != nil {
+		return err
+	}
+	if err := load_harnesses(local_repo_path, local_goroot_path); err != nil {
+		return err
+	}
+	h, ok := find_harness(key)
+	if !ok {
+		return fmt.Errorf("%s was saved by %s, which is not one of the target's harnesses", input, key)
+	}
+	harness, harness_dir := h.Name, h.Directory
+
+	if err := os.MkdirAll(asset_dir, 0o755); err != nil {
+		return err
+	}
+	input_id := filepath.Base(input)
+	script, err := generate_repro_script(asset_dir, h.Key(), harness, harness_dir, input_id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Reproducing %s with %s in %s\n", input, harness, harness_dir)
+	var output bytes.Buffer
+	run_err := ActiveRunner.Run(ctx, RunSpec{
+		Name:    script,
+		Script:  script,
+		Workdir: "/scripts",
+		Mounts: []Mount{
+			{HostPath: asset_dir, ContainerPath: "/scripts"},
+			{HostPath: local_goroot_path, ContainerPath: "/go_backup"},
+			{HostPath: filepath.Dir(input), ContainerPath: "/crash"},
+		},
+		Stdout: &output,
+		Stderr: &output,
+	})
+	if ctx.Err() != nil {
+		return fmt.Errorf("reproduction interrupted: %w", ctx.Err())
+	}
+
+	crashes, err := triage.Parse(bytes.NewReader(output.Bytes()))
+	if err != nil {
+		return err
+	}
+	if len(crashes) == 0 {
+		os.Stdout.Write(output.Bytes())
+		if run_err != nil {
+			return fmt.Errorf("could not replay %s: %w", input, run_err)
+		}
+		return fmt.Errorf("%s/%s did not reproduce", harness, input_id)
+	}
+
+	c := crashes[0]
+	fmt.Printf("\nReproduced %s/%s in %s\n\n", harness, input_id, c.Package)
+	fmt.Printf("panic: %s\n\n", c.Panic)
+	for _, line := range c.Stack {
+		fmt.Println(line)
+	}
+	return nil
+}
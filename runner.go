@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// name of the image built from nosy-fuzzer.Dockerfile
+const image_name = "nosy-neighbor"
+
+// Mount binds a host directory to a path inside the environment a script
+// runs in
+type Mount struct {
+	HostPath      string
+	ContainerPath string
+}
+
+// RunSpec describes a script to run inside the target's environment. All
+// paths other than the mount host paths are paths inside the environment.
+type RunSpec struct {
+	// Name identifies the run in logs and step summaries
+	Name string
+	// Script is the script to run, relative to Workdir unless absolute
+	Script  string
+	Workdir string
+	Mounts  []Mount
+	// Env holds extra KEY=value environment variables for the script
+	Env []string
+	// CPUs caps the cores available to the script, 0 means no limit
+	CPUs float64
+	// Stdout and Stderr default to the terminal when nil
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Runner is an execution backend for the scripts nosy generates. Backends
+// decide how the target is isolated from the host, if at all.
+type Runner interface {
+	// Name returns the name used to select the backend
+	Name() string
+	// BuildImage prepares the environment scripts will run in
+	BuildImage(dockerfile string) error
+	// Run runs a script with the given mounts and returns a *CommandError
+	// if it exited non-zero. Scripts see the backend's name in $NOSY_RUNNER.
+	// Cancelling ctx stops the script and everything it started.
+	Run(ctx context.Context, spec RunSpec) error
+}
+
+// ActiveRunner is the backend selected for the current command
+var ActiveRunner Runner
+
+// registered runner backends
+var runners = map[string]func() Runner{
+	"docker": func() Runner { return &container_runner{engine: "docker"} },
+	"podman": func() Runner { return &container_runner{engine: "podman"} },
+	"local":  func() Runner { return &local_runner{} },
+}
+
+// runner_names returns the registered backends in a stable order
+func runner_names() []string {
+	var names []string
+	for name := range runners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// select_runner picks a backend by name. An empty name falls back to the
+// target config and then to docker.
+func select_runner(name string) (Runner, error) {
+	if name == "" {
+		name = TargetConfig.Runner
+	}
+	if name == "" {
+		name = "docker"
+	}
+	new_runner, ok := runners[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown runner %q, expected one of: %s", name, strings.Join(runner_names(), ", "))
+	}
+	return new_runner(), nil
+}
+
+// stdout returns where the run's output and command line should go
+func (spec RunSpec) stdout() io.Writer {
+	if spec.Stdout != nil {
+		return spec.Stdout
+	}
+	return os.Stdout
+}
+
+// gomaxprocs returns the GOMAXPROCS setting matching the spec's CPU quota,
+// so go test does not start more fuzz workers than it has cores for
+func (spec RunSpec) gomaxprocs() string {
+	return strconv.Itoa(int(math.Ceil(spec.CPUs)))
+}
+
+// resolve_script returns the absolute path of the script for spec
+func (spec RunSpec) resolve_script() string {
+	if strings.HasPrefix(spec.Script, "/") || spec.Workdir == "" {
+		return spec.Script
+	}
+	return strings.TrimSuffix(spec.Workdir, "/") + "/" + spec.Script
+}
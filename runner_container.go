@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// container_runner runs scripts in containers built from
+// nosy-fuzzer.Dockerfile with a docker compatible cli (docker or podman)
+type container_runner struct {
+	engine string
+}
+
+func (r *container_runner) Name() string {
+	return r.engine
+}
+
+func (r *container_runner) BuildImage(dockerfile string) error {
+	args := []string{"build", "-t", image_name, "-f", dockerfile, "."}
+	cmd := exec.Command(r.engine, args...)
+	if r.engine == "docker" {
+		cmd.Env = append(os.Environ(), "BUILDKIT=1")
+	}
+	display := r.engine + " " + strings.Join(args, " ")
+	fmt.Println(display)
+	return run_and_capture(cmd, display)
+}
+
+// container_name returns a unique, valid container name for a run so it can
+// be stopped when nosy is interrupted
+func container_name(run_name string) string {
+	name := []byte(fmt.Sprintf("nosy-%s-%s", TargetConfig.TargetRepo, run_name))
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			name[i] = '_'
+		}
+	}
+	return fmt.Sprintf("%s-%d-%d", name, os.Getpid(), atomic.AddUint64(&container_count, 1))
+}
+
+var container_count uint64
+
+func (r *container_runner) Run(ctx context.Context, spec RunSpec) error {
+	name := container_name(spec.Name)
+	args := []string{"run", "--rm", "--name", name}
+	if spec.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(spec.CPUs, 'f', -1, 64), "-e", "GOMAXPROCS="+spec.gomaxprocs())
+	}
+	if spec.Workdir != "" {
+		args = append(args, "--workdir", spec.Workdir)
+	}
+	for _, m := range spec.Mounts {
+		args = append(args, "-v", fmt.Sprintf("%s:%s", m.HostPath, m.ContainerPath))
+	}
+	args = append(args, "-e", "NOSY_RUNNER="+r.engine)
+	for _, e := range spec.Env {
+		args = append(args, "-e", e)
+	}
+	args = append(args, image_name, spec.Script)
+
+	cmd := exec.Command(r.engine, args...)
+	cmd.Stdout = spec.Stdout
+	cmd.Stderr = spec.Stderr
+	display := r.engine + " " + strings.Join(args, " ")
+	fmt.Fprintln(spec.stdout(), display)
+	// killing the cli client would leave the container running
+	return run_and_capture_ctx(ctx, cmd, display, func() {
+		exec.Command(r.engine, "kill", name).Run()
+	})
+}
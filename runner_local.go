@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// container_gopath is $GOPATH inside the nosy-neighbor image
+const container_gopath = "/go"
+
+// local_runner runs scripts directly on the host without any sandboxing.
+// Container paths in the script are rewritten to the host paths of their
+// mounts, and paths that would have been private to the container (such as
+// an unmounted $GOPATH) are backed by a scratch directory for the run.
+type local_runner struct{}
+
+func (r *local_runner) Name() string {
+	return "local"
+}
+
+// BuildImage checks that the tools the scripts expect are installed on the
+// host, there is no image to build
+func (r *local_runner) BuildImage(dockerfile string) error {
+	for _, tool := range []string{"bash", "git", TargetConfig.TargetGoVersion} {
+		if tool == "" {
+			continue
+		}
+		if _, err := exec.LookPath(tool); err != nil {
+			return fmt.Errorf("local runner requires %s on $PATH: %w", tool, err)
+		}
+	}
+	return nil
+}
+
+func (r *local_runner) Run(ctx context.Context, spec RunSpec) error {
+	scratch, err := os.MkdirTemp("", "nosy-local-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratch)
+
+	// anything under the container's $GOPATH that is not mounted lives in
+	// the scratch directory, just like it would in a fresh container
+	mounts := append([]Mount{}, spec.Mounts...)
+	if _, mounted := map_container_path(container_gopath, mounts); !mounted {
+		gopath := filepath.Join(scratch, "go")
+		if err := os.MkdirAll(gopath, 0o755); err != nil {
+			return err
+		}
+		mounts = append(mounts, Mount{HostPath: gopath, ContainerPath: container_gopath})
+	}
+
+	script_path, ok := map_container_path(spec.resolve_script(), mounts)
+	if !ok {
+		return fmt.Errorf("script %s is not inside any mount", spec.Script)
+	}
+	script, err := os.ReadFile(script_path)
+	if err != nil {
+		return err
+	}
+	local_script := filepath.Join(scratch, filepath.Base(script_path))
+	if err := os.WriteFile(local_script, []byte(rewrite_container_paths(string(script), mounts)), 0o755); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("bash", local_script)
+	if spec.Workdir != "" {
+		cmd.Dir, _ = map_container_path(spec.Workdir, mounts)
+	}
+	gopath, _ := map_container_path(container_gopath, mounts)
+	cmd.Env = append(os.Environ(), "GOPATH="+gopath, "NOSY_RUNNER=local")
+	if spec.CPUs > 0 {
+		// there is no quota outside of a container, but go test will at
+		// least keep its fuzz workers within it
+		cmd.Env = append(cmd.Env, "GOMAXPROCS="+spec.gomaxprocs())
+	}
+	cmd.Env = append(cmd.Env, spec.Env...)
+	cmd.Stdout = spec.Stdout
+	cmd.Stderr = spec.Stderr
+	start_process_group(cmd)
+	display := fmt.Sprintf("bash %s (%s)", local_script, spec.resolve_script())
+	fmt.Fprintln(spec.stdout(), display)
+	return run_and_capture_ctx(ctx, cmd, display, func() {
+		kill_process_group(cmd)
+	})
+}
+
+// map_container_path translates a path inside the container to the host path
+// of the mount that holds it
+func map_container_path(path string, mounts []Mount) (string, bool) {
+	best := -1
+	for i, m := range mounts {
+		if path == m.ContainerPath || strings.HasPrefix(path, strings.TrimSuffix(m.ContainerPath, "/")+"/") {
+			if best == -1 || len(m.ContainerPath) > len(mounts[best].ContainerPath) {
+				best = i
+			}
+		}
+	}
+	if best == -1 {
+		return path, false
+	}
+	m := mounts[best]
+	return m.HostPath + strings.TrimPrefix(path, strings.TrimSuffix(m.ContainerPath, "/")), true
+}
+
+// is_path_char reports whether c can be part of a path in a generated script
+func is_path_char(c byte) bool {
+	return c == '/' || c == '.' || c == '_' || c == '-' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// rewrite_container_paths replaces every absolute container path in script
+// that falls under one of the mounts with the matching host path. A mount
+// only matches whole path elements, so a /go mount does not rewrite
+// /go_backup.
+func rewrite_container_paths(script string, mounts []Mount) string {
+	// try the most specific mount first
+	sorted := append([]Mount{}, mounts...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i].ContainerPath) > len(sorted[j].ContainerPath)
+	})
+
+	var out strings.Builder
+	for i := 0; i < len(script); {
+		matched := false
+		if i == 0 || !is_path_char(script[i-1]) {
+			for _, m := range sorted {
+				prefix := strings.TrimSuffix(m.ContainerPath, "/")
+				if !strings.HasPrefix(script[i:], prefix) {
+					continue
+				}
+				end := i + len(prefix)
+				if end < len(script) && script[end] != '/' && is_path_char(script[end]) {
+					continue
+				}
+				out.WriteString(m.HostPath)
+				i = end
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			out.WriteByte(script[i])
+			i++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSelectRunner(t *testing.T) {
+	defer func(runner string) { TargetConfig.Runner = runner }(TargetConfig.Runner)
+	tests := []struct {
+		name   string
+		config string
+		want   string
+		err    bool
+	}{
+		{"", "", "docker", false},
+		{"", "podman", "podman", false},
+		{"local", "podman", "local", false},
+		{"docker", "", "docker", false},
+		{"vm", "", "", true},
+	}
+	for _, tt := range tests {
+		TargetConfig.Runner = tt.config
+		r, err := select_runner(tt.name)
+		if (err != nil) != tt.err {
+			t.Errorf("select_runner(%q) with %q configured: error %v, want error %t", tt.name, tt.config, err, tt.err)
+			continue
+		}
+		if err == nil && r.Name() != tt.want {
+			t.Errorf("select_runner(%q) with %q configured = %s, want %s", tt.name, tt.config, r.Name(), tt.want)
+		}
+	}
+}
+
+func TestRunSpec(t *testing.T) {
+	tests := []struct {
+		spec       RunSpec
+		script     string
+		gomaxprocs string
+	}{
+		{RunSpec{Script: "fuzz.sh", Workdir: "/scripts", CPUs: 2}, "/scripts/fuzz.sh", "2"},
+		{RunSpec{Script: "fuzz.sh", Workdir: "/scripts/", CPUs: 1.5}, "/scripts/fuzz.sh", "2"},
+		{RunSpec{Script: "/src/gen_harness.sh", Workdir: "/scripts", CPUs: 0.5}, "/src/gen_harness.sh", "1"},
+		{RunSpec{Script: "fuzz.sh"}, "fuzz.sh", "0"},
+	}
+	for _, tt := range tests {
+		if got := tt.spec.resolve_script(); got != tt.script {
+			t.Errorf("resolve_script(%+v) = %q, want %q", tt.spec, got, tt.script)
+		}
+		if got := tt.spec.gomaxprocs(); got != tt.gomaxprocs {
+			t.Errorf("gomaxprocs(%v) = %q, want %q", tt.spec.CPUs, got, tt.gomaxprocs)
+		}
+	}
+}
+
+func TestContainerName(t *testing.T) {
+	defer func(repo string) { TargetConfig.TargetRepo = repo }(TargetConfig.TargetRepo)
+	TargetConfig.TargetRepo = "go-ethereum"
+	valid := regexp.MustCompile(`^nosy-go-ethereum-fuzz_Fuzz_Nosy_Header__-cb6647e4.sh-[0-9]+-[0-9]+$`)
+	a := container_name("fuzz_Fuzz_Nosy_Header__-cb6647e4.sh")
+	b := container_name("fuzz Fuzz/Nosy:Header__-cb6647e4.sh")
+	for _, name := range []string{a, b} {
+		if !valid.MatchString(name) {
+			t.Errorf("container_name() = %q, want a match of %s", name, valid)
+		}
+	}
+	if a == b {
+		t.Errorf("two runs share the container name %q", a)
+	}
+}
+
+var test_mounts = []Mount{
+	{HostPath: "/home/u/nosy/fuzzing_directory/fake/go", ContainerPath: "/go"},
+	{HostPath: "/home/u/nosy/fuzzing_directory/fake/src", ContainerPath: "/src"},
+	{HostPath: "/home/u/nosy/fuzzing_directory/fake/results", ContainerPath: "/go/results/"},
+}
+
+func TestMapContainerPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		want   string
+		mapped bool
+	}{
+		{"/go", "/home/u/nosy/fuzzing_directory/fake/go", true},
+		{"/go/src/example.com/fake", "/home/u/nosy/fuzzing_directory/fake/go/src/example.com/fake", true},
+		{"/go/results/Fuzz_Nosy_Sum__", "/home/u/nosy/fuzzing_directory/fake/results/Fuzz_Nosy_Sum__", true},
+		{"/src/gen_harness.sh", "/home/u/nosy/fuzzing_directory/fake/src/gen_harness.sh", true},
+		{"/go_backup", "/go_backup", false},
+		{"/scripts/fuzz.sh", "/scripts/fuzz.sh", false},
+	}
+	for _, tt := range tests {
+		got, mapped := map_container_path(tt.path, test_mounts)
+		if got != tt.want || mapped != tt.mapped {
+			t.Errorf("map_container_path(%q) = %q, %t, want %q, %t", tt.path, got, mapped, tt.want, tt.mapped)
+		}
+	}
+}
+
+func TestRewriteContainerPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{
+			name:   "command",
+			script: "cd /go/src/example.com/fake\n",
+			want:   "cd /home/u/nosy/fuzzing_directory/fake/go/src/example.com/fake\n",
+		},
+		{
+			name:   "most specific mount",
+			script: "cp crash /go/results/Fuzz_Nosy_Sum__/\n",
+			want:   "cp crash /home/u/nosy/fuzzing_directory/fake/results/Fuzz_Nosy_Sum__/\n",
+		},
+		{
+			name:   "whole elements only",
+			script: "cp -r /go_backup /gopher /src2\n",
+			want:   "cp -r /go_backup /gopher /src2\n",
+		},
+		{
+			name:   "inside a word",
+			script: "GOPATH=/go go run $(find /src/cmd -name '*.go')\n",
+			want:   "GOPATH=/home/u/nosy/fuzzing_directory/fake/go go run $(find /home/u/nosy/fuzzing_directory/fake/src/cmd -name '*.go')\n",
+		},
+		{
+			name:   "not a path",
+			script: "echo example.com/go/src\n",
+			want:   "echo example.com/go/src\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewrite_container_paths(tt.script, test_mounts); got != tt.want {
+				t.Errorf("rewrite_container_paths(%q) =\n%q\nwant\n%q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+// the local runner runs a script from a mount on the host, with its
+// container paths rewritten and a scratch $GOPATH
+func TestLocalRunner(t *testing.T) {
+	dir := t.TempDir()
+	scripts := filepath.Join(dir, "scripts")
+	out_dir := filepath.Join(dir, "out")
+	for _, d := range []string{scripts, out_dir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := `echo "$NOSY_RUNNER $GOMAXPROCS $EXTRA" > /out/env
+test -d "$GOPATH"
+echo "$GOPATH" >> /out/env
+pwd >> /out/env
+exit $CODE
+`
+	if err := os.WriteFile(filepath.Join(scripts, "run.sh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		err  bool
+	}{
+		{"success", "0", false},
+		{"failure", "3", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := (&local_runner{}).Run(context.Background(), RunSpec{
+				Name:    "run.sh",
+				Script:  "run.sh",
+				Workdir: "/scripts",
+				Mounts: []Mount{
+					{HostPath: scripts, ContainerPath: "/scripts"},
+					{HostPath: out_dir, ContainerPath: "/out"},
+				},
+				Env:    []string{"EXTRA=x", "CODE=" + tt.code},
+				CPUs:   2,
+				Stdout: &stdout,
+				Stderr: &stdout,
+			})
+			var cmd_err *CommandError
+			if tt.err {
+				if !errors.As(err, &cmd_err) || cmd_err.ExitCode != 3 {
+					t.Fatalf("Run() = %v, want a *CommandError with exit code 3", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filepath.Join(out_dir, "env"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("script wrote %q", data)
+			}
+			if lines[0] != "local 2 x" {
+				t.Errorf("NOSY_RUNNER GOMAXPROCS EXTRA = %q, want \"local 2 x\"", lines[0])
+			}
+			if !strings.HasPrefix(lines[1], os.TempDir()) || !strings.HasSuffix(lines[1], "/go") {
+				t.Errorf("GOPATH = %q, want a scratch directory", lines[1])
+			}
+			if lines[2] != scripts {
+				t.Errorf("working directory %q, want %q", lines[2], scripts)
+			}
+			if _, err := os.Stat(lines[1]); err == nil {
+				t.Errorf("scratch GOPATH %s was left behind", lines[1])
+			}
+		})
+	}
+}
+
+// a command whose stdout and stderr go to the same writer keeps its output
+// whole, and its stderr tail
+func TestRunAndCaptureCombined(t *testing.T) {
+	var output bytes.Buffer
+	script := `for i in $(seq 1 200); do echo "out $i"; echo "err $i" >&2; done; exit 1`
+	cmd := exec.Command("bash", "-c", script)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := run_and_capture(cmd, "bash")
+	var cmd_err *CommandError
+	if !errors.As(err, &cmd_err) || cmd_err.ExitCode != 1 {
+		t.Fatalf("run_and_capture() = %v, want a *CommandError with exit code 1", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 400 {
+		t.Errorf("output has %d lines, want 400", len(lines))
+	}
+	if !strings.Contains(cmd_err.StderrTail, "err 200") {
+		t.Errorf("stderr tail %q lacks the last line", cmd_err.StderrTail)
+	}
+}
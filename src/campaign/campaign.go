@@ -0,0 +1,298 @@
+package campaign
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// version of the campaign state file format
+const StateVersion = 1
+
+// name of the campaign state file in a target's fuzzing directory
+const StateFile = "campaign.json"
+
+type Status string
+
+const (
+	Pending     Status = "pending"
+	Running     Status = "running"
+	Done        Status = "done"
+	Crashed     Status = "crashed"
+	BuildFailed Status = "build-failed"
+)
+
+// Finished reports whether a harness with this status needs no more fuzzing
+// in the current campaign
+func (s Status) Finished() bool {
+	return s == Done || s == Crashed || s == BuildFailed
+}
+
+// Harness tracks the progress of one fuzz harness through a campaign
+type Harness struct {
+	// Key tells apart harnesses of the same name in different packages
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	Directory string `json:"directory"`
+	Status    Status `json:"status"`
+	// total time spent fuzzing the harness, across interrupted runs
+	Seconds    float64    `json:"seconds"`
+	Runs       int        `json:"runs"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	// seconds of the budget given to the last run, and whether that run was
+	// still finding new interesting inputs when it ended
+	SliceSeconds int  `json:"slice_seconds,omitempty"`
+	Growing      bool `json:"growing"`
+	// what go test reported for the last run, and the executions of every
+	// run so far
+	Stats      *Stats `json:"stats,omitempty"`
+	TotalExecs int64  `json:"total_execs"`
+	// crashes of the last run by triage bucket
+	Crashes []CrashSummary `json:"crashes,omitempty"`
+}
+
+// CrashSummary counts the crashes of one triage bucket in a harness run
+type CrashSummary struct {
+	Bucket string `json:"bucket"`
+	Panic  string `json:"panic"`
+	Count  int    `json:"count"`
+	// the bucket was not in the saved buckets when the run finished
+	New bool `json:"new"`
+}
+
+// State is the persistent record of a fuzzing campaign. It is safe for
+// concurrent use and every update is written straight to disk so an
+// interrupted campaign can be resumed.
+type State struct {
+	mu   sync.Mutex
+	path string
+
+	Version   int        `json:"version"`
+	Target    string     `json:"target"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Harnesses []*Harness `json:"harnesses"`
+	// set when the campaign runs on a total time budget
+	Budget *Budget `json:"budget,omitempty"`
+	// how the campaign was last started, used to estimate when it finishes
+	Jobs              int `json:"jobs,omitempty"`
+	SecondsPerHarness int `json:"seconds_per_harness,omitempty"`
+}
+
+// New returns an empty campaign for target that will be saved to path
+func New(path string, target string) *State {
+	return &State{
+		path:      path,
+		Version:   StateVersion,
+		Target:    target,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Load reads the campaign state saved at path. It returns an error wrapping
+// fs.ErrNotExist if there is no campaign to resume.
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &State{path: path}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Version != StateVersion {
+		return nil, fmt.Errorf("%s: unsupported campaign state version %d", path, s.Version)
+	}
+	return s, nil
+}
+
+// LoadOrNew resumes the campaign saved at path, or starts a new one if there
+// is none. Harnesses left running by a campaign that was killed are put back
+// to pending.
+func LoadOrNew(path string, target string) (*State, error) {
+	s, err := Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(path, target), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.reset_running()
+	return s, nil
+}
+
+// reset_running puts every running harness back to pending and gives its
+// slice back to the budget, like Finish does for an interrupted run. A
+// campaign killed before it could finish its runs leaves them running.
+func (s *State) reset_running() {
+	for _, h := range s.Harnesses {
+		if h.Status == Running {
+			h.Status = Pending
+			s.refund(h)
+		}
+	}
+}
+
+// Path returns where the state is saved
+func (s *State) Path() string {
+	return s.path
+}
+
+// Save writes the state to disk
+func (s *State) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+// save writes the state atomically so a crash never leaves a truncated file.
+// The caller must hold s.mu.
+func (s *State) save() error {
+	s.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".campaign-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Update runs fn with the state locked and saves the result
+func (s *State) Update(fn func(s *State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s)
+	return s.save()
+}
+
+// View runs fn with the state locked, fn must not modify the state
+func (s *State) View(fn func(s *State)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s)
+}
+
+// Harness returns the harness with the given key or nil. The caller must
+// hold the lock, i.e. be inside Update or View.
+func (s *State) Harness(key string) *Harness {
+	for _, h := range s.Harnesses {
+		if h.Key == key {
+			return h
+		}
+	}
+	return nil
+}
+
+// AddHarness registers a harness as pending unless the campaign already
+// tracks it. Campaigns saved before harnesses had keys track them by name
+// and directory. The caller must hold the lock.
+func (s *State) AddHarness(key string, name string, directory string) *Harness {
+	if h := s.Harness(key); h != nil {
+		h.Directory = directory
+		return h
+	}
+	for _, h := range s.Harnesses {
+		if h.Key == "" && h.Name == name && h.Directory == directory {
+			h.Key = key
+			return h
+		}
+	}
+	h := &Harness{Key: key, Name: name, Directory: directory, Status: Pending}
+	s.Harnesses = append(s.Harnesses, h)
+	return h
+}
+
+// Start marks a harness as running
+func (s *State) Start(key string) error {
+	return s.Update(func(s *State) {
+		h := s.Harness(key)
+		if h == nil {
+			return
+		}
+		now := time.Now().UTC()
+		h.Status = Running
+		h.Runs++
+		h.StartedAt = &now
+		h.FinishedAt = nil
+		h.Error = ""
+	})
+}
+
+// Finish records the outcome of a harness run that took elapsed. An
+// interrupted run is put back to pending so it is picked up on resume.
+func (s *State) Finish(key string, status Status, elapsed time.Duration, run_err error) error {
+	return s.Update(func(s *State) {
+		h := s.Harness(key)
+		if h == nil {
+			return
+		}
+		h.Status = status
+		h.Seconds += elapsed.Seconds()
+		if status == Pending {
+			s.refund(h)
+		}
+		if status.Finished() {
+			now := time.Now().UTC()
+			h.FinishedAt = &now
+		}
+		if run_err != nil {
+			h.Error = run_err.Error()
+		}
+	})
+}
+
+// RecordCrashes replaces the crashes recorded for a harness with those of its
+// last run
+func (s *State) RecordCrashes(key string, crashes []CrashSummary) error {
+	return s.Update(func(s *State) {
+		h := s.Harness(key)
+		if h == nil {
+			return
+		}
+		h.Crashes = crashes
+	})
+}
+
+// Counts returns the number of harnesses in each status
+func (s *State) Counts() map[Status]int {
+	counts := map[Status]int{}
+	s.View(func(s *State) {
+		for _, h := range s.Harnesses {
+			counts[h.Status]++
+		}
+	})
+	return counts
+}
+
+// StatusFromOutput classifies the output of a go test -fuzz run
+func StatusFromOutput(output string) Status {
+	switch {
+	case strings.Contains(output, "[build failed]"),
+		strings.Contains(output, "[setup failed]"):
+		return BuildFailed
+	case strings.Contains(output, "--- FAIL:"):
+		return Crashed
+	default:
+		return Done
+	}
+}
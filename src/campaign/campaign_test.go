@@ -0,0 +1,252 @@
+package campaign
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a saved campaign reads back as it was written
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	s := New(path, "fake")
+	err := s.Update(func(s *State) {
+		s.Jobs = 4
+		s.AddHarness("Fuzz_Nosy_Sum__-ae310132", "Fuzz_Nosy_Sum__", "parse")
+		s.AddHarness("Fuzz_Nosy_Header__-cb6647e4", "Fuzz_Nosy_Header__", "other")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start("Fuzz_Nosy_Sum__-ae310132"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Finish("Fuzz_Nosy_Sum__-ae310132", Crashed, 3*time.Second, errors.New("exit status 1")); err != nil {
+		t.Fatal(err)
+	}
+	crashes := []CrashSummary{{Bucket: "6c6af616619d7c83", Panic: "runtime error: integer divide by zero", Count: 2, New: true}}
+	if err := s.RecordCrashes("Fuzz_Nosy_Sum__-ae310132", crashes); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Path() != path || loaded.Target != "fake" || loaded.Jobs != 4 || len(loaded.Harnesses) != 2 {
+		t.Fatalf("loaded %+v", loaded)
+	}
+	h := loaded.Harness("Fuzz_Nosy_Sum__-ae310132")
+	if h.Status != Crashed || h.Runs != 1 || h.Seconds != 3 || h.Error != "exit status 1" || h.FinishedAt == nil {
+		t.Errorf("loaded harness %+v", h)
+	}
+	if len(h.Crashes) != 1 || h.Crashes[0] != crashes[0] {
+		t.Errorf("loaded crashes %+v, want %+v", h.Crashes, crashes)
+	}
+	if h := loaded.Harness("Fuzz_Nosy_Header__-cb6647e4"); h.Status != Pending || h.Directory != "other" {
+		t.Errorf("loaded harness %+v", h)
+	}
+
+	// the state is written in place of the old one, no temporary file is
+	// left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("state directory holds %d files, want only %s", len(entries), StateFile)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     string
+		not_exist bool
+	}{
+		{"missing", "", true},
+		{"not json", "{", false},
+		{"other version", `{"version": 2}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), StateFile)
+			if tt.state != "" {
+				if err := os.WriteFile(path, []byte(tt.state), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := Load(path)
+			if err == nil {
+				t.Fatal("Load() succeeded, want an error")
+			}
+			if errors.Is(err, fs.ErrNotExist) != tt.not_exist {
+				t.Errorf("Load() = %v, not exist %t", err, tt.not_exist)
+			}
+			if !tt.not_exist && !strings.Contains(err.Error(), path) {
+				t.Errorf("Load() = %v, want the path in the error", err)
+			}
+			s, err := LoadOrNew(path, "fake")
+			if (err == nil) != tt.not_exist {
+				t.Errorf("LoadOrNew() = %v", err)
+			}
+			if err == nil && len(s.Harnesses) != 0 {
+				t.Errorf("LoadOrNew() of a missing campaign has harnesses %+v", s.Harnesses)
+			}
+		})
+	}
+}
+
+func TestAddHarness(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []*Harness
+		// the harness added, as key, name, directory
+		add  [3]string
+		want []*Harness
+	}{
+		{
+			name: "new",
+			add:  [3]string{"Sum-1", "Sum", "parse"},
+			want: []*Harness{{Key: "Sum-1", Name: "Sum", Directory: "parse", Status: Pending}},
+		},
+		{
+			name:     "known keeps its progress",
+			existing: []*Harness{{Key: "Sum-1", Name: "Sum", Directory: "parse", Status: Done, Runs: 2}},
+			add:      [3]string{"Sum-1", "Sum", "parse/v2"},
+			want:     []*Harness{{Key: "Sum-1", Name: "Sum", Directory: "parse/v2", Status: Done, Runs: 2}},
+		},
+		{
+			name:     "saved before keys",
+			existing: []*Harness{{Name: "Sum", Directory: "parse", Status: Done}},
+			add:      [3]string{"Sum-1", "Sum", "parse"},
+			want:     []*Harness{{Key: "Sum-1", Name: "Sum", Directory: "parse", Status: Done}},
+		},
+		{
+			name:     "same name in another package",
+			existing: []*Harness{{Key: "Sum-1", Name: "Sum", Directory: "parse", Status: Done}},
+			add:      [3]string{"Sum-2", "Sum", "other"},
+			want: []*Harness{
+				{Key: "Sum-1", Name: "Sum", Directory: "parse", Status: Done},
+				{Key: "Sum-2", Name: "Sum", Directory: "other", Status: Pending},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(filepath.Join(t.TempDir(), StateFile), "fake")
+			s.Harnesses = tt.existing
+			s.AddHarness(tt.add[0], tt.add[1], tt.add[2])
+			if !reflect.DeepEqual(s.Harnesses, tt.want) {
+				t.Errorf("harnesses %s, want %s", harness_list(s.Harnesses), harness_list(tt.want))
+			}
+		})
+	}
+}
+
+func TestFinish(t *testing.T) {
+	tests := []struct {
+		status   Status
+		finished bool
+	}{
+		{Done, true},
+		{Crashed, true},
+		{BuildFailed, true},
+		// an interrupted run is picked up again on resume
+		{Pending, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			s := New(filepath.Join(t.TempDir(), StateFile), "fake")
+			s.AddHarness("Sum-1", "Sum", "parse")
+			for run := 1; run <= 2; run++ {
+				if err := s.Start("Sum-1"); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.Finish("Sum-1", tt.status, 2*time.Second, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			h := s.Harness("Sum-1")
+			if h.Status != tt.status || h.Status.Finished() != tt.finished || (h.FinishedAt != nil) != tt.finished {
+				t.Errorf("harness %+v, want status %s finished %t", h, tt.status, tt.finished)
+			}
+			if h.Runs != 2 || h.Seconds != 4 {
+				t.Errorf("%d runs in %gs, want 2 runs in 4s", h.Runs, h.Seconds)
+			}
+			if counts := s.Counts(); counts[tt.status] != 1 || len(counts) != 1 {
+				t.Errorf("Counts() = %v", counts)
+			}
+		})
+	}
+}
+
+// concurrent jobs update the state at the same time, none of their updates
+// is lost
+func TestConcurrentUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	s := New(path, "fake")
+	const harnesses = 8
+	for i := 0; i < harnesses; i++ {
+		s.AddHarness(fmt.Sprintf("h-%d", i), "h", fmt.Sprint(i))
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < harnesses; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			for run := 0; run < 5; run++ {
+				if err := s.Start(key); err != nil {
+					t.Error(err)
+				}
+				if err := s.Finish(key, Done, time.Second, nil); err != nil {
+					t.Error(err)
+				}
+			}
+		}(fmt.Sprintf("h-%d", i))
+	}
+	wg.Wait()
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range loaded.Harnesses {
+		if h.Runs != 5 || h.Status != Done {
+			t.Errorf("harness %s: %d runs, %s, want 5 runs, done", h.Key, h.Runs, h.Status)
+		}
+	}
+}
+
+func TestStatusFromOutput(t *testing.T) {
+	tests := []struct {
+		output string
+		want   Status
+	}{
+		{"ok  \texample.com/fake/parse\t2.038s\n", Done},
+		{"--- FAIL: Fuzz_Nosy_Sum__ (0.02s)\n", Crashed},
+		{"FAIL\texample.com/fake/parse [build failed]\n", BuildFailed},
+		{"FAIL\texample.com/fake/parse [setup failed]\n", BuildFailed},
+	}
+	for _, tt := range tests {
+		if got := StatusFromOutput(tt.output); got != tt.want {
+			t.Errorf("StatusFromOutput(%q) = %s, want %s", tt.output, got, tt.want)
+		}
+	}
+}
+
+// harness_list prints the harnesses rather than their addresses
+func harness_list(harnesses []*Harness) string {
+	var list []string
+	for _, h := range harnesses {
+		list = append(list, fmt.Sprintf("%+v", *h))
+	}
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,127 @@
+package campaign
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Progress is one progress line of a go test -fuzz run
+type Progress struct {
+	Elapsed     time.Duration
+	Execs       int64
+	ExecsPerSec int64
+	// inputs added to the corpus during this run, and the corpus size
+	NewInteresting int
+	Corpus         int
+}
+
+// Run is what the progress lines of a go test -fuzz run report
+type Run struct {
+	Workers  int
+	Progress []Progress
+}
+
+// Stats summarizes a fuzzing run
+type Stats struct {
+	Seconds float64 `json:"seconds"`
+	Execs   int64   `json:"execs"`
+	// average over the whole run
+	ExecsPerSec    float64 `json:"execs_per_sec"`
+	Corpus         int     `json:"corpus"`
+	NewInteresting int     `json:"new_interesting"`
+	Workers        int     `json:"workers"`
+}
+
+var (
+	elapsed_re     = regexp.MustCompile(`^fuzz: elapsed: ([0-9hms.]+)`)
+	execs_re       = regexp.MustCompile(`execs: (\d+) \((\d+)/sec\)`)
+	interesting_re = regexp.MustCompile(`new interesting: (\d+) \(total: (\d+)\)`)
+	workers_re     = regexp.MustCompile(`now fuzzing with (\d+) workers`)
+)
+
+// ParseRun reads the progress lines out of a go test -fuzz output
+func ParseRun(output string) Run {
+	var run Run
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		run.Add(scanner.Text())
+	}
+	return run
+}
+
+// Add records a line of go test -fuzz output and reports whether it was a
+// progress line
+func (r *Run) Add(line string) bool {
+	line = strings.TrimSpace(line)
+	m := elapsed_re.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	if w := workers_re.FindStringSubmatch(line); w != nil {
+		r.Workers, _ = strconv.Atoi(w[1])
+		return true
+	}
+	// the baseline coverage lines carry no stats
+	e := execs_re.FindStringSubmatch(line)
+	n := interesting_re.FindStringSubmatch(line)
+	if e == nil || n == nil {
+		return false
+	}
+	elapsed, err := time.ParseDuration(m[1])
+	if err != nil {
+		return false
+	}
+	p := Progress{Elapsed: elapsed}
+	p.Execs, _ = strconv.ParseInt(e[1], 10, 64)
+	p.ExecsPerSec, _ = strconv.ParseInt(e[2], 10, 64)
+	p.NewInteresting, _ = strconv.Atoi(n[1])
+	p.Corpus, _ = strconv.Atoi(n[2])
+	r.Progress = append(r.Progress, p)
+	return true
+}
+
+// Stats summarizes the run from its last progress line
+func (r Run) Stats() Stats {
+	stats := Stats{Workers: r.Workers}
+	if len(r.Progress) == 0 {
+		return stats
+	}
+	last := r.Progress[len(r.Progress)-1]
+	stats.Seconds = last.Elapsed.Seconds()
+	stats.Execs = last.Execs
+	stats.Corpus = last.Corpus
+	stats.NewInteresting = last.NewInteresting
+	if stats.Seconds > 0 {
+		stats.ExecsPerSec = float64(last.Execs) / stats.Seconds
+	} else {
+		stats.ExecsPerSec = float64(last.ExecsPerSec)
+	}
+	return stats
+}
+
+// StillGrowing reports whether a run was still finding new interesting inputs
+// in the last quarter of its time, a run that plateaued early does not
+// deserve more of the budget
+func (r Run) StillGrowing() bool {
+	progress := r.Progress
+	if len(progress) == 0 {
+		return false
+	}
+	last := progress[len(progress)-1]
+	if len(progress) == 1 {
+		return last.NewInteresting > 0
+	}
+	threshold := last.Elapsed * 3 / 4
+	before := 0
+	for _, p := range progress {
+		if p.Elapsed > threshold {
+			break
+		}
+		before = p.NewInteresting
+	}
+	return last.NewInteresting > before
+}
@@ -0,0 +1,150 @@
+package campaign
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunAdd(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		progress bool
+		want     Run
+	}{
+		{
+			name:     "stats",
+			line:     "fuzz: elapsed: 6s, execs: 64125 (9576/sec), new interesting: 27 (total: 28)",
+			progress: true,
+			want: Run{Progress: []Progress{{
+				Elapsed: 6 * time.Second, Execs: 64125, ExecsPerSec: 9576, NewInteresting: 27, Corpus: 28,
+			}}},
+		},
+		{
+			name:     "indented",
+			line:     "\tfuzz: elapsed: 1m3s, execs: 10 (0/sec), new interesting: 0 (total: 1)  ",
+			progress: true,
+			want: Run{Progress: []Progress{{
+				Elapsed: 63 * time.Second, Execs: 10, NewInteresting: 0, Corpus: 1,
+			}}},
+		},
+		{
+			name:     "workers",
+			line:     "fuzz: elapsed: 0s, gathering baseline coverage: 1/1 completed, now fuzzing with 4 workers",
+			progress: true,
+			want:     Run{Workers: 4},
+		},
+		{
+			name: "baseline",
+			line: "fuzz: elapsed: 0s, gathering baseline coverage: 0/13 completed",
+		},
+		{
+			name: "minimizing",
+			line: "fuzz: elapsed: 0s, minimizing",
+		},
+		{
+			name: "not progress",
+			line: "ok  	example.com/fake/parse	2.038s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var run Run
+			if got := run.Add(tt.line); got != tt.progress {
+				t.Errorf("Add() = %t, want %t", got, tt.progress)
+			}
+			if run.Workers != tt.want.Workers {
+				t.Errorf("Workers = %d, want %d", run.Workers, tt.want.Workers)
+			}
+			if len(run.Progress) != len(tt.want.Progress) {
+				t.Fatalf("Progress = %+v, want %+v", run.Progress, tt.want.Progress)
+			}
+			for i := range run.Progress {
+				if run.Progress[i] != tt.want.Progress[i] {
+					t.Errorf("Progress[%d] = %+v, want %+v", i, run.Progress[i], tt.want.Progress[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRun(t *testing.T) {
+	tests := []struct {
+		log      string
+		lines    int
+		stats    Stats
+		growing  bool
+		finished Status
+	}{
+		{
+			log:   "growing.log",
+			lines: 4,
+			stats: Stats{
+				Seconds: 11, Execs: 98092, ExecsPerSec: 98092.0 / 11, Corpus: 30, NewInteresting: 29, Workers: 4,
+			},
+			growing:  true,
+			finished: Done,
+		},
+		{
+			log:      "plateau.log",
+			lines:    1,
+			stats:    Stats{Seconds: 2, Execs: 47598, ExecsPerSec: 23799, Corpus: 13, Workers: 1},
+			finished: Done,
+		},
+		{
+			// the run crashed before a second passed, the rate go test
+			// reported is used as is
+			log:      "crashed.log",
+			lines:    1,
+			stats:    Stats{Execs: 38, ExecsPerSec: 1608, Corpus: 1, Workers: 1},
+			finished: Crashed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.log, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.log))
+			if err != nil {
+				t.Fatal(err)
+			}
+			run := ParseRun(string(data))
+			if len(run.Progress) != tt.lines {
+				t.Errorf("got %d progress lines, want %d", len(run.Progress), tt.lines)
+			}
+			if got := run.Stats(); got != tt.stats {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.stats)
+			}
+			if got := run.StillGrowing(); got != tt.growing {
+				t.Errorf("StillGrowing() = %t, want %t", got, tt.growing)
+			}
+			if got := StatusFromOutput(string(data)); got != tt.finished {
+				t.Errorf("StatusFromOutput() = %s, want %s", got, tt.finished)
+			}
+		})
+	}
+}
+
+func TestStillGrowing(t *testing.T) {
+	at := func(seconds int, interesting int) Progress {
+		return Progress{Elapsed: time.Duration(seconds) * time.Second, NewInteresting: interesting}
+	}
+	tests := []struct {
+		name     string
+		progress []Progress
+		want     bool
+	}{
+		{"no progress", nil, false},
+		{"single line with new inputs", []Progress{at(3, 2)}, true},
+		{"single line without new inputs", []Progress{at(3, 0)}, false},
+		{"new inputs in the last quarter", []Progress{at(3, 1), at(6, 1), at(9, 1), at(12, 4)}, true},
+		{"plateaued early", []Progress{at(3, 5), at(6, 8), at(9, 8), at(12, 8)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Run{Progress: tt.progress}).StillGrowing(); got != tt.want {
+				t.Errorf("StillGrowing() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
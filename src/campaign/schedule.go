@@ -0,0 +1,191 @@
+package campaign
+
+import (
+	"fmt"
+	"time"
+)
+
+// Budget is the total fuzzing time of a campaign and how it was handed out
+type Budget struct {
+	TotalSeconds int `json:"total_seconds"`
+	// seconds handed out so far, time of interrupted slices is given back
+	SpentSeconds int `json:"spent_seconds"`
+	// slice every harness gets in the first round
+	InitialSeconds int        `json:"initial_seconds"`
+	Round          int        `json:"round"`
+	Decisions      []Decision `json:"decisions"`
+}
+
+// Remaining returns the seconds left to hand out
+func (b *Budget) Remaining() int {
+	if b.SpentSeconds >= b.TotalSeconds {
+		return 0
+	}
+	return b.TotalSeconds - b.SpentSeconds
+}
+
+// Decision records why a harness was given a slice of the budget, or why
+// the rest of the budget was left unused when Harness is empty
+type Decision struct {
+	Round   int       `json:"round"`
+	Harness string    `json:"harness,omitempty"`
+	Seconds int       `json:"seconds"`
+	Reason  string    `json:"reason"`
+	At      time.Time `json:"at"`
+}
+
+// Slice is a run of a harness, by its key, for a number of seconds
+type Slice struct {
+	Harness string
+	Seconds int
+}
+
+// SetBudget starts handing out total seconds between the campaign's
+// harnesses. Half of the budget is split evenly in a first round, the rest
+// goes to the harnesses still finding new inputs. A resumed campaign with
+// the same budget keeps what it already spent. The caller must hold the
+// lock.
+func (s *State) SetBudget(total int) {
+	if s.Budget != nil && s.Budget.TotalSeconds == total {
+		return
+	}
+	initial := 1
+	if len(s.Harnesses) > 0 && total/(2*len(s.Harnesses)) > initial {
+		initial = total / (2 * len(s.Harnesses))
+	}
+	s.Budget = &Budget{TotalSeconds: total, InitialSeconds: initial}
+}
+
+// NextRound hands out the next round of the budget. Harnesses that have not
+// run yet, or whose run was interrupted, get their slice first. After that
+// the remaining budget is split between the harnesses whose last run was
+// still finding new interesting inputs, each getting at most twice its last
+// slice so it is checked again before it takes the whole budget. Every
+// harness that gets no slice is recorded as a decision too. It returns nil
+// once the budget is spent or no harness is still growing.
+func (s *State) NextRound() ([]Slice, error) {
+	var slices []Slice
+	err := s.Update(func(s *State) {
+		b := s.Budget
+		if b == nil {
+			return
+		}
+		b.Round++
+		allocate := func(h *Harness, seconds int, reason string) {
+			if seconds > b.Remaining() {
+				seconds = b.Remaining()
+			}
+			if seconds < 1 {
+				b.Decisions = append(b.Decisions, Decision{
+					Round:   b.Round,
+					Harness: h.Key,
+					Reason:  fmt.Sprintf("no slice for %s, %ds of the budget left", reason, b.Remaining()),
+					At:      time.Now().UTC(),
+				})
+				return
+			}
+			b.SpentSeconds += seconds
+			h.SliceSeconds = seconds
+			slices = append(slices, Slice{Harness: h.Key, Seconds: seconds})
+			b.Decisions = append(b.Decisions, Decision{
+				Round:   b.Round,
+				Harness: h.Key,
+				Seconds: seconds,
+				Reason:  reason,
+				At:      time.Now().UTC(),
+			})
+		}
+
+		for _, h := range s.Harnesses {
+			switch {
+			case h.Status == Pending && h.SliceSeconds > 0:
+				allocate(h, h.SliceSeconds, "resuming an interrupted slice")
+			case h.Status == Pending:
+				allocate(h, b.InitialSeconds, "initial slice")
+			}
+		}
+		if len(slices) > 0 {
+			return
+		}
+
+		var growing []*Harness
+		for _, h := range s.Harnesses {
+			if h.Status == Done && h.Growing && h.Stats != nil {
+				growing = append(growing, h)
+			}
+		}
+		reason := ""
+		switch {
+		case b.Remaining() == 0:
+			reason = "budget spent"
+		case len(growing) == 0:
+			reason = "no harness is still finding new interesting inputs"
+		case b.Remaining()/len(growing) < 1:
+			reason = fmt.Sprintf("%ds left is too little to split between %d harnesses", b.Remaining(), len(growing))
+		}
+		if reason != "" {
+			b.Decisions = append(b.Decisions, Decision{
+				Round:   b.Round,
+				Seconds: b.Remaining(),
+				Reason:  reason,
+				At:      time.Now().UTC(),
+			})
+			return
+		}
+		share := b.Remaining() / len(growing)
+		for _, h := range growing {
+			// harnesses that ran before the campaign had a budget have no
+			// last slice, they are capped as if they had the initial one
+			last := h.SliceSeconds
+			if last == 0 {
+				last = b.InitialSeconds
+			}
+			seconds := share
+			if seconds > 2*last {
+				seconds = 2 * last
+			}
+			allocate(h, seconds, fmt.Sprintf("still growing, %d new interesting inputs in its last run", h.Stats.NewInteresting))
+		}
+	})
+	return slices, err
+}
+
+// refund gives the slice of an interrupted harness run back to the budget.
+// The caller must hold the lock.
+func (s *State) refund(h *Harness) {
+	if s.Budget == nil {
+		return
+	}
+	s.Budget.SpentSeconds -= h.SliceSeconds
+	if s.Budget.SpentSeconds < 0 {
+		s.Budget.SpentSeconds = 0
+	}
+}
+
+// RecordRun keeps the stats of the last run of a harness, whether it was
+// still growing decides if it gets more of the budget
+func (s *State) RecordRun(key string, run Run) error {
+	return s.Update(func(s *State) {
+		h := s.Harness(key)
+		if h == nil {
+			return
+		}
+		stats := run.Stats()
+		h.Stats = &stats
+		h.TotalExecs += stats.Execs
+		h.Growing = run.StillGrowing()
+	})
+}
+
+// RecordLive updates the stats of a running harness as its progress lines
+// come in, so the campaign can be watched from another process
+func (s *State) RecordLive(key string, run Run) error {
+	return s.Update(func(s *State) {
+		h := s.Harness(key)
+		if h == nil || h.Status != Running {
+			return
+		}
+		stats := run.Stats()
+		h.Stats = &stats
+	})
+}
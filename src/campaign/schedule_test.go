@@ -0,0 +1,231 @@
+package campaign
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// new_campaign returns a campaign of the given harnesses with a budget of
+// total seconds, saved to a temporary directory
+func new_campaign(t *testing.T, total int, keys ...string) *State {
+	t.Helper()
+	s := New(filepath.Join(t.TempDir(), StateFile), "fake")
+	err := s.Update(func(s *State) {
+		for _, key := range keys {
+			s.AddHarness(key, key, "parse")
+		}
+		s.SetBudget(total)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// run_round hands out the next round and finishes every slice, growing or
+// not
+func run_round(t *testing.T, s *State, growing map[string]bool) []Slice {
+	t.Helper()
+	slices, err := s.NextRound()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, slice := range slices {
+		if err := s.Start(slice.Harness); err != nil {
+			t.Fatal(err)
+		}
+		interesting := 0
+		if growing[slice.Harness] {
+			interesting = 1
+		}
+		run := Run{Progress: []Progress{{Elapsed: time.Duration(slice.Seconds) * time.Second, NewInteresting: interesting}}}
+		if err := s.RecordRun(slice.Harness, run); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Finish(slice.Harness, Done, time.Duration(slice.Seconds)*time.Second, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return slices
+}
+
+func TestNextRound(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int
+		keys    []string
+		growing map[string]bool
+		// slices handed out in each round, the last round hands out none
+		rounds [][]Slice
+		// reason of the decision that ended the campaign
+		reason string
+	}{
+		{
+			name:   "initial round splits half",
+			total:  40,
+			keys:   []string{"a", "b"},
+			rounds: [][]Slice{{{"a", 10}, {"b", 10}}, nil},
+			reason: "no harness is still finding new interesting inputs",
+		},
+		{
+			name:    "growing capped at twice the last slice",
+			total:   100,
+			keys:    []string{"a", "b"},
+			growing: map[string]bool{"a": true},
+			rounds:  [][]Slice{{{"a", 25}, {"b", 25}}, {{"a", 50}}, nil},
+			reason:  "budget spent",
+		},
+		{
+			name:    "growing share of the rest",
+			total:   100,
+			keys:    []string{"a", "b", "c", "d"},
+			growing: map[string]bool{"a": true, "b": true, "c": true},
+			rounds:  [][]Slice{{{"a", 12}, {"b", 12}, {"c", 12}, {"d", 12}}, {{"a", 17}, {"b", 17}, {"c", 17}}, nil},
+			reason:  "1s left is too little to split between 3 harnesses",
+		},
+		{
+			name:   "budget smaller than the harnesses",
+			total:  2,
+			keys:   []string{"a", "b", "c"},
+			rounds: [][]Slice{{{"a", 1}, {"b", 1}}, nil},
+			reason: "budget spent",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new_campaign(t, tt.total, tt.keys...)
+			for i, want := range tt.rounds {
+				if got := run_round(t, s, tt.growing); !reflect.DeepEqual(got, want) {
+					t.Fatalf("round %d: slices %v, want %v", i+1, got, want)
+				}
+			}
+			b := s.Budget
+			if b.SpentSeconds > b.TotalSeconds {
+				t.Errorf("spent %ds of a %ds budget", b.SpentSeconds, b.TotalSeconds)
+			}
+			last := b.Decisions[len(b.Decisions)-1]
+			if last.Harness != "" || last.Reason != tt.reason {
+				t.Errorf("last decision %+v, want %q", last, tt.reason)
+			}
+		})
+	}
+}
+
+// a harness that gets no slice is recorded as a decision of its own
+func TestNextRoundNoSlice(t *testing.T) {
+	s := new_campaign(t, 2, "a", "b", "c")
+	if _, err := s.NextRound(); err != nil {
+		t.Fatal(err)
+	}
+	var skipped []string
+	for _, d := range s.Budget.Decisions {
+		if d.Seconds == 0 {
+			skipped = append(skipped, d.Harness+": "+d.Reason)
+		}
+	}
+	want := []string{"c: no slice for initial slice, 0s of the budget left"}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("decisions without a slice %q, want %q", skipped, want)
+	}
+}
+
+// harnesses that ran before the campaign had a budget are capped as if
+// their last slice was the initial one
+func TestNextRoundWithoutLastSlice(t *testing.T) {
+	s := new_campaign(t, 100, "a", "b")
+	err := s.Update(func(s *State) {
+		for _, h := range s.Harnesses {
+			h.Status = Done
+			h.Growing = true
+			h.Stats = &Stats{NewInteresting: 3}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices, err := s.NextRound()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Slice{{"a", 50}, {"b", 50}}
+	if !reflect.DeepEqual(slices, want) {
+		t.Errorf("slices %v, want %v", slices, want)
+	}
+}
+
+func TestRefund(t *testing.T) {
+	tests := []struct {
+		name string
+		// how the first run of harness a ends
+		interrupt func(t *testing.T, s *State) *State
+	}{
+		{
+			name: "interrupted run",
+			interrupt: func(t *testing.T, s *State) *State {
+				if err := s.Finish("a", Pending, 3*time.Second, nil); err != nil {
+					t.Fatal(err)
+				}
+				return s
+			},
+		},
+		{
+			// nosy fuzz was killed and left the harness running, resuming
+			// puts it back to pending
+			name: "killed campaign",
+			interrupt: func(t *testing.T, s *State) *State {
+				resumed, err := LoadOrNew(s.Path(), "fake")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return resumed
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new_campaign(t, 40, "a", "b")
+			slices, err := s.NextRound()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(slices) != 2 {
+				t.Fatalf("first round slices %v, want 2", slices)
+			}
+			if err := s.Start("a"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Start("b"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Finish("b", Done, 10*time.Second, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			s = tt.interrupt(t, s)
+			var status Status
+			s.View(func(s *State) { status = s.Harness("a").Status })
+			if status != Pending {
+				t.Fatalf("harness a is %s, want %s", status, Pending)
+			}
+			if s.Budget.SpentSeconds != 10 {
+				t.Errorf("spent %ds after the refund, want 10", s.Budget.SpentSeconds)
+			}
+
+			slices, err = s.NextRound()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []Slice{{"a", 10}}
+			if !reflect.DeepEqual(slices, want) {
+				t.Errorf("resumed slices %v, want %v", slices, want)
+			}
+			last := s.Budget.Decisions[len(s.Budget.Decisions)-1]
+			if !strings.Contains(last.Reason, "interrupted") {
+				t.Errorf("resumed slice decision %q, want an interrupted slice", last.Reason)
+			}
+		})
+	}
+}
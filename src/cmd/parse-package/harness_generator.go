@@ -6,6 +6,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"go/types"
 	"io"
@@ -16,9 +17,9 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/infosecual/go-fuzz-fill-utils/fuzzer"
-	"golang.org/x/tools/imports"
 )
 
 type paramSupport uint
@@ -75,11 +76,12 @@ func stripPointers(t types.Type, depth int) types.Type {
 }
 
 // checkArgTypesSupport reports the level of support across target
-// function arguments.
-func checkArgTypesSupport(allWrapperParams []*types.Var) (paramSupport, string) {
+// function arguments. Without support it also returns the offending
+// parameter type and the skip report reason for it.
+func checkArgTypesSupport(allWrapperParams []*types.Var) (paramSupport, string, string) {
 	res := unknown
 	if len(allWrapperParams) == 0 {
-		return nativeSupport, ""
+		return nativeSupport, "", ""
 	}
 	min := func(a, b paramSupport) paramSupport {
 		if a < b {
@@ -136,18 +138,20 @@ func checkArgTypesSupport(allWrapperParams []*types.Var) (paramSupport, string)
 		switch t.Underlying().(type) {
 		case *types.Interface:
 			if !fuzzer.SupportedInterfaces[t.String()] {
-				return noSupport, v.Type().String()
+				return noSupport, v.Type().String(), ReasonInterfaceParam
 			}
 			res = min(fillRequired, res)
-		case *types.Signature, *types.Chan:
-			return noSupport, v.Type().String()
+		case *types.Signature:
+			return noSupport, v.Type().String(), ReasonFuncParam
+		case *types.Chan:
+			return noSupport, v.Type().String(), ReasonChanParam
 		}
 
 		// If we didn't easily find a problematic type above, we'll guess that cmd/go supports it,
 		// and let cmd/go complain if it needs to for more complex cases not handled above.
 		res = min(nativeSupport, res)
 	}
-	return res, ""
+	return res, "", ""
 }
 
 // emitArgs emits the arguments needed to call a signature, including handling renaming arguments
@@ -264,7 +268,7 @@ func avoidCollision(v *types.Var, i int, localPkg *types.Package, allWrapperPara
 // type such as interface{}.
 func emitIndependentWrappers(pkgPath string, pkgFuncs *TargetPackage, wrapperPkgName string, harness_directory string) ([]byte, error) {
 	if len(pkgFuncs.TargetFunctions) == 0 {
-		return nil, fmt.Errorf("%w: 0 matching functions")
+		return nil, fmt.Errorf("0 matching functions")
 	}
 
 	// prepare the output
@@ -322,18 +326,12 @@ func emitIndependentWrappers(pkgPath string, pkgFuncs *TargetPackage, wrapperPkg
 	// We only return an error if all fail.
 	var firstErr error
 	var success bool
+	constructors := supportedConstructors(pkgFuncs)
 	for _, function := range pkgFuncs.TargetFunctions {
-		var constructors []TargetFunction
-		for _, constructor := range pkgFuncs.TargetConstructors {
-			// Skip over any candidate constructors with unsupported params.
-			ctorInputParams := args(constructor.TypesFunc)
-			support, _ := checkArgTypesSupport(ctorInputParams)
-			if support == noSupport {
-				continue
-			}
-			constructors = append(constructors, constructor)
+		if reason, detail := function.ExcludeReason(TargetConfig); reason != "" {
+			report_function(function, "", reason, detail)
+			continue
 		}
-
 		err := emitIndependentWrapper(emit, function, constructors, false, harness_directory)
 		if err != nil && firstErr == nil {
 			firstErr = err
@@ -349,6 +347,29 @@ func emitIndependentWrappers(pkgPath string, pkgFuncs *TargetPackage, wrapperPkg
 	return buf.Bytes(), nil
 }
 
+// supportedConstructors returns the constructors of a package that can be
+// inserted into a wrapper
+func supportedConstructors(pkgFuncs *TargetPackage) []TargetFunction {
+	var constructors []TargetFunction
+	for _, constructor := range pkgFuncs.TargetConstructors {
+		// Skip over any candidate constructors with unsupported params.
+		ctorInputParams := args(constructor.TypesFunc)
+		support, _, _ := checkArgTypesSupport(ctorInputParams)
+		if support == noSupport {
+			continue
+		}
+		// nor call one taking a type in the ignore list
+		if usesIgnoredType(constructor.TypesFunc, TargetConfig.IgnoreTypes) != nil {
+			continue
+		}
+		if usesSubstitutedPackage(constructor.TypesFunc, TargetConfig.SubstitutePackages) != nil {
+			continue
+		}
+		constructors = append(constructors, constructor)
+	}
+	return constructors
+}
+
 // externalQualifier can be used as types.Qualifier in calls to types.TypeString and similar.
 func externalQualifier(p *types.Package) string {
 	// always return the package name, which
@@ -423,7 +444,7 @@ func constructorMatch(recv *types.Var, possibleCtor TargetFunction) (ctorMatch,
 	ctorSig, ok := possibleCtor.TypesFunc.Type().(*types.Signature)
 	if !ok {
 		return ctorMatch{}, fmt.Errorf("function %s is not *types.Signature (%+v)",
-			possibleCtor, possibleCtor.TypesFunc)
+			possibleCtor.Name, possibleCtor.TypesFunc)
 	}
 
 	ctorResultN, secondResultIsErr := constructorResult(possibleCtor.TypesFunc)
@@ -506,17 +527,58 @@ func hasPath(s string) bool {
 	return false
 }
 
-// emitIndependentWrapper emits one fuzzing wrapper if possible.
+// wrapperPlan holds the decisions made when wrapping a function under test:
+// the wrapper's name, the parameters the wrapper takes and whether the
+// receiver of a method is created through a constructor. It is shared by the
+// fuzz harnesses and the regression tests generated from their crashes, so
+// both name and build the arguments the same way.
+type wrapperPlan struct {
+	function   TargetFunction
+	f          *types.Func
+	wrappedSig *types.Signature
+	localPkg   *types.Package
+	qualifyAll bool
+
+	// recv is the receiver of a wrapped method, nil for plain functions
+	recv        *types.Var
+	recvName    string
+	wrapperName string
+
+	// inputParams are the wrapper's parameters: a constructor's parameters
+	// or the receiver, followed by the parameters of the function under test
+	inputParams []*types.Var
+	paramReprs  []argRep
+
+	support          paramSupport
+	unsupportedParam string
+	// unsupportedReason is the skip report reason for unsupportedParam
+	unsupportedReason string
+
+	ctorReplace ctorMatch
+	// collisionOffset is where the parameters of the function under test
+	// start within inputParams
+	collisionOffset int
+}
+
+// planWrapper works out how to wrap one function under test.
 // It takes a list of possible constructors to insert into the wrapper body if the
 // constructor is suitable for creating the receiver of a wrapped method.
 // qualifyAll indicates if all variables should be qualified with their package.
-func emitIndependentWrapper(emit emitFunc, function TargetFunction, constructors []TargetFunction, qualifyAll bool, harness_directory string) error {
+// Functions that cannot be wrapped are reported with a *skipError.
+func planWrapper(function TargetFunction, constructors []TargetFunction, qualifyAll bool) (*wrapperPlan, error) {
 	f := function.TypesFunc
 	wrappedSig, ok := f.Type().(*types.Signature)
 	if !ok {
-		return fmt.Errorf("function %s is not *types.Signature (%+v)", function, f)
+		return nil, fmt.Errorf("function %s is not *types.Signature (%+v)", function.Name, f)
 	}
-	localPkg := f.Pkg()
+	plan := &wrapperPlan{
+		function:   function,
+		f:          f,
+		wrappedSig: wrappedSig,
+		localPkg:   f.Pkg(),
+		qualifyAll: qualifyAll,
+	}
+	localPkg := plan.localPkg
 
 	// Set up types.Qualifier funcs we can use with the types package
 	// to scope variables by a package or not.
@@ -524,27 +586,24 @@ func emitIndependentWrapper(emit emitFunc, function TargetFunction, constructors
 
 	// Get our receiver, which might be nil if we don't have a receiver
 	recv := wrappedSig.Recv()
+	plan.recv = recv
 
 	// Determine our wrapper name, which includes the receiver's type if we are wrapping a method.
-	var wrapperName string
 	var err error
 	if recv == nil {
-		wrapperName = fmt.Sprintf("Fuzz_Nosy_%s__", f.Name())
+		plan.wrapperName = fmt.Sprintf("Fuzz_Nosy_%s__", f.Name())
 	} else {
 		n, err := namedType(recv)
 		if err != nil {
 			// output to stderr, but don't treat as fatal error.
 			fmt.Fprintf(os.Stderr, "genfuzzfuncs: warning: createWrapper: failed to determine receiver type: %v: %v\n", recv, err)
-			return nil
+			return nil, &skipError{ReasonUnknownReceiver, err.Error()}
 		}
 		recvNamedTypeLocalName := types.TypeString(n.Obj().Type(), localQualifier)
-		wrapperName = fmt.Sprintf("Fuzz_Nosy_%s_%s__", recvNamedTypeLocalName, f.Name())
+		plan.wrapperName = fmt.Sprintf("Fuzz_Nosy_%s_%s__", recvNamedTypeLocalName, f.Name())
+		plan.recvName = recvNamedTypeLocalName
 	}
 
-	// Start building up our list of parameters we will use in input
-	// parameters to the new wrapper func we are about to emit.
-	var inputParams []*types.Var
-
 	// Check if we have a receiver for the function under test (that is, testing a method)
 	// and then see if we can replace the receiver by finding
 	// a suitable constructor and "promoting" the constructor's arguments up into the wrapper's parameter list.
@@ -561,60 +620,152 @@ func emitIndependentWrapper(emit emitFunc, function TargetFunction, constructors
 	// 			r := strings.NewReader(s)
 	// 			r.Read(b)
 	// 		})
-	var ctorReplace ctorMatch
 	if recv != nil {
 		var paramsToAdd []*types.Var
-		ctorReplace, paramsToAdd, err = constructorReplace(recv, constructors)
+		plan.ctorReplace, paramsToAdd, err = constructorReplace(recv, constructors)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		inputParams = append(inputParams, paramsToAdd...)
+		plan.inputParams = append(plan.inputParams, paramsToAdd...)
 	}
 
 	// Also add in the parameters for the function under test.
 	for i := 0; i < wrappedSig.Params().Len(); i++ {
 		v := wrappedSig.Params().At(i)
-		inputParams = append(inputParams, v)
+		plan.inputParams = append(plan.inputParams, v)
 	}
-	if len(inputParams) == 0 {
+	if len(plan.inputParams) == 0 {
 		// skip this wrapper, not useful for fuzzing if no inputs (no receiver, no parameters).
-		return fmt.Errorf("%w: %s has 0 input params", function.Name)
+		return nil, &skipError{ReasonNoInputs, ""}
 	}
 
-	paramReprs := make([]argRep, len(inputParams))
-	for i, v := range inputParams {
+	plan.paramReprs = make([]argRep, len(plan.inputParams))
+	for i, v := range plan.inputParams {
 		typeStringWithSelector := types.TypeString(v.Type(), defaultQualifier)
-		paramName := avoidCollision(v, i, localPkg, inputParams)
-		paramReprs[i] = argRep{argName: paramName, argType: typeStringWithSelector, v: v}
+		paramName := avoidCollision(v, i, localPkg, plan.inputParams)
+		plan.paramReprs[i] = argRep{argName: paramName, argType: typeStringWithSelector, v: v}
 	}
 
 	// Check if we have an interface or function pointer in our desired parameters,
 	// which we can't fill with values during fuzzing.
+	plan.support, plan.unsupportedParam, plan.unsupportedReason = checkArgTypesSupport(plan.inputParams)
+
+	// collisionOffset tracks how far we are into the parameters of the final fuzz function signature.
+	// (For a constructor call, it will be zero because for the final fuzz function,
+	// the signature starts with any constructor parameters. For the function under test,
+	// the offset will by the length of the signature of constructor, if any, or zero if no constructor.
+	// This is because the parameters for the function under test follow any constructor parameters
+	// in the final fuzz function signature.
+	// TODO: collisionOffset is a bit quick & dirty. Probably should track a more direct
+	// (original name, provenance) -> new name mapping, or perhaps simplify the logic
+	// so that we never use original names.
+	if recv != nil {
+		if plan.ctorReplace.sig != nil {
+			plan.collisionOffset = plan.ctorReplace.sig.Params().Len()
+		} else {
+			// We have a receiver, but we are not injecting a constructor (perhaps because
+			// the option was disabled, or perhaps because we did not find a suitable constructor)
+			// Reserve space in our naming space for the receiver.
+			// TODO: we test this case, but add comment here describing which test would fail.
+			plan.collisionOffset = 1
+		}
+	}
+	return plan, nil
+}
+
+// emitCall emits the constructor for the receiver, if the plan uses one,
+// followed by the call to the function under test. onCtorErr is emitted as
+// the body of the check of a constructor's error.
+func emitCall(emit emitFunc, plan *wrapperPlan, onCtorErr string) {
+	if plan.recv != nil && plan.ctorReplace.sig != nil {
+		// insert our constructor!
+		emit("\t%s", avoidCollision(plan.recv, 0, plan.localPkg, plan.inputParams))
+		if plan.ctorReplace.secondResultIsErr {
+			emit(", err")
+		}
+		emit(" := ")
+		if plan.qualifyAll {
+			emit("%s.%s(", plan.localPkg.Name(), plan.ctorReplace.f.Name())
+		} else {
+			emit("%s(", plan.ctorReplace.f.Name())
+		}
+		emitArgs(emit, plan.ctorReplace.sig, 0, plan.localPkg, plan.inputParams)
+		emit(")\n")
+		if plan.ctorReplace.secondResultIsErr {
+			emit("\tif err != nil {\n")
+			emit("\t\t%s\n", onCtorErr)
+			emit("\t}\n")
+		}
+	}
+
+	// Emit the call to the wrapped function.
+	emitWrappedFunc(emit, plan.f, plan.wrappedSig, "", plan.collisionOffset, plan.qualifyAll, plan.inputParams, plan.localPkg)
+}
+
+// manifestEntry describes the harness generated from plan
+func manifestEntry(plan *wrapperPlan, harness_directory string) Harness {
+	h := Harness{
+		Name:      plan.wrapperName,
+		Package:   plan.function.PackagePath,
+		Directory: harness_directory,
+		File:      harness_directory + "/Fuzz_Nosy_test.go",
+		Function:  plan.f.Name(),
+		Receiver:  plan.recvName,
+		Signature: types.ObjectString(plan.f, types.RelativeTo(plan.localPkg)),
+		Support:   FillSupport,
+	}
+	if plan.support == nativeSupport {
+		h.Support = NativeSupport
+	}
+	if plan.recv != nil && plan.ctorReplace.sig != nil {
+		h.Constructor = plan.ctorReplace.f.Name()
+	}
+	return h
+}
 
-	support, unsupportedParam := checkArgTypesSupport(inputParams)
-	if support == noSupport {
+// emitIndependentWrapper emits one fuzzing wrapper if possible.
+// It takes a list of possible constructors to insert into the wrapper body if the
+// constructor is suitable for creating the receiver of a wrapped method.
+// qualifyAll indicates if all variables should be qualified with their package.
+// Every function ends up in the skip report, with the reason it was skipped
+// if it was.
+func emitIndependentWrapper(emit emitFunc, function TargetFunction, constructors []TargetFunction, qualifyAll bool, harness_directory string) error {
+	plan, err := planWrapper(function, constructors, qualifyAll)
+	if plan == nil {
+		var skip *skipError
+		if errors.As(err, &skip) {
+			report_function(function, "", skip.reason, skip.detail)
+		} else {
+			report_function(function, "", ReasonError, err.Error())
+		}
+		return err
+	}
+	wrapperName := plan.wrapperName
+
+	if plan.support == noSupport {
 		// skip this wrapper.
-		emit("// skipping %s because parameters include func, chan, or unsupported interface: %v\n\n", wrapperName, unsupportedParam)
-		return fmt.Errorf("%w: %s takes %s", function.Name, unsupportedParam)
+		emit("// skipping %s because parameters include func, chan, or unsupported interface: %v\n\n", wrapperName, plan.unsupportedParam)
+		report_function(function, "", plan.unsupportedReason, plan.unsupportedParam)
+		return fmt.Errorf("%s takes %s", function.Name, plan.unsupportedParam)
 	}
 
-	// alternate func name and dir, should fix this later
-	FuzzTargets = append(FuzzTargets, wrapperName)
-	FuzzTargets = append(FuzzTargets, harness_directory)
+	// list the harness in the manifest the fuzz stage reads
+	Manifest.Harnesses = append(Manifest.Harnesses, manifestEntry(plan, harness_directory))
+	report_function(function, wrapperName, ReasonHarness, "")
 
 	// Start emitting the wrapper function!
 	// Start with the func declaration and the start of f.Fuzz.
 	emit("func %s(f *testing.F) {\n", wrapperName)
 	emit("\tf.Fuzz(func(t *testing.T, ")
 
-	switch support {
+	switch plan.support {
 	case nativeSupport:
 		// The result for this line will end up similar to:
 		//    f.Fuzz(func(t *testing.T, s string, i int) {
 		// Iterate over the our input parameters and emit.
 		// If we are a method, this includes either an object that is wrapped receiver's type,
 		// or it includes the parameters for a constructor if we found a suitable one.
-		for i, p := range paramReprs {
+		for i, p := range plan.paramReprs {
 			// want: foo string, bar int
 			if i > 0 {
 				emit(", ")
@@ -626,7 +777,7 @@ func emitIndependentWrapper(emit emitFunc, function TargetFunction, constructors
 		// Always crashing on a nil receiver is not particularly interesting, so emit the code to avoid.
 		// Also avoid nil crash if we have any other pointer parameters.
 		// A user can easily delete all or part this boilerplate if they don't want particular nil checks.
-		emitNilChecks(emit, inputParams, localPkg)
+		emitNilChecks(emit, plan.inputParams, plan.localPkg)
 	case fillRequired:
 		// This is something not yet supported by cmd/go, but we can shim it via go-fuzz-fill-utils.
 		// The result will up similar to:
@@ -636,90 +787,41 @@ func emitIndependentWrapper(emit emitFunc, function TargetFunction, constructors
 		//    fz.Fill(&map)
 		// First, finish the line we are on.
 		emit("data []byte) {\n")
-		// generate a type provider object for filling
-		type_provider_decl := `
-		tp, fill_err := GetTypeProvider(data)
-		if fill_err != nil {
-			return
-		}
-		`
-		emit(type_provider_decl)
-
-		// Second, declare the variables we need to fill.
-		for _, p := range paramReprs {
-			emit("\t\tvar %s %s\n", p.argName, p.argType)
-			emit("\t\tfill_err = tp.Fill(&%s)\n", p.argName)
-			emit("\t\tif fill_err != nil {\n")
-			emit("\t\t\treturn\n")
-			emit("\t\t}\n")
-		}
-		// Third, create a go-fuzz-fill-utils.Fuzzer
-		//emit("\t\tfz := fuzzer.NewFuzzer(data)\n")
-		// Fourth, emit a potentially wide Fill call for all the variables we declared.
-		//emit("\t\tfz.Fill(")
-		//for i, p := range paramReprs {
-		//	if i > 0 {
-		//		emit(", ")
-		//	}
-		//	emit("&%s", p.paramName)
-		//}
-		//emit(")\n")
+		// Second, declare and fill the variables we need.
+		emitFill(emit, plan, "return")
 		// Avoid nil crash if we have pointer parameters.
-		emitNilChecks(emit, inputParams, localPkg)
+		emitNilChecks(emit, plan.inputParams, plan.localPkg)
 		emit("\n")
 	default:
-		panic(fmt.Sprintf("unexpected result from checkParamSupport: %v", support))
-	}
-
-	// Emit a constructor if we have one.
-	// collisionOffset tracks how far we are into the parameters of the final fuzz function signature.
-	// (For a constructor call, it will be zero because for the final fuzz function,
-	// the signature starts with any constructor parameters. For the function under test,
-	// the offset will by the length of the signature of constructor, if any, or zero if no constructor.
-	// This is because the parameters for the function under test follow any constructor parameters
-	// in the final fuzz function signature.
-	// TODO: collisionOffset is a bit quick & dirty. Probably should track a more direct
-	// (original name, provenance) -> new name mapping, or perhaps simplify the logic
-	// so that we never use original names.
-	collisionOffset := 0
-	if recv != nil {
-		if ctorReplace.sig != nil {
-			// insert our constructor!
-			emit("\t%s", avoidCollision(recv, 0, localPkg, inputParams))
-			if ctorReplace.secondResultIsErr {
-				emit(", err")
-			}
-			emit(" := ")
-			if qualifyAll {
-				emit("%s.%s(", localPkg.Name(), ctorReplace.f.Name())
-			} else {
-				emit("%s(", ctorReplace.f.Name())
-			}
-			emitArgs(emit, ctorReplace.sig, 0, localPkg, inputParams)
-			emit(")\n")
-			if ctorReplace.secondResultIsErr {
-				emit("\tif err != nil {\n")
-				emit("\t\treturn\n")
-				emit("\t}\n")
-			}
-			collisionOffset = ctorReplace.sig.Params().Len()
-		} else {
-			// We have a receiver, but we are not injecting a constructor (perhaps because
-			// the option was disabled, or perhaps because we did not find a suitable constructor)
-			// Reserve space in our naming space for the receiver.
-			// TODO: we test this case, but add comment here describing which test would fail.
-			collisionOffset = 1
-		}
+		panic(fmt.Sprintf("unexpected result from checkParamSupport: %v", plan.support))
 	}
 
-	// Emit the call to the wrapped function.
-	emitWrappedFunc(emit, f, wrappedSig, "", collisionOffset, qualifyAll, inputParams, localPkg)
+	// Emit a constructor if we have one, and the call to the wrapped function.
+	emitCall(emit, plan, "return")
 	emit("\t})\n")
 	emit("}\n\n")
 
 	return nil
 }
 
+// emitFill emits the type provider for data and the declaration and fill of
+// every wrapper parameter. onFillErr is emitted when data cannot be decoded.
+func emitFill(emit emitFunc, plan *wrapperPlan, onFillErr string) {
+	// generate a type provider object for filling
+	emit("\n\t\ttp, fill_err := GetTypeProvider(data)\n")
+	emit("\t\tif fill_err != nil {\n")
+	emit("\t\t\t%s\n", onFillErr)
+	emit("\t\t}\n")
+
+	for _, p := range plan.paramReprs {
+		emit("\t\tvar %s %s\n", p.argName, p.argType)
+		emit("\t\tfill_err = tp.Fill(&%s)\n", p.argName)
+		emit("\t\tif fill_err != nil {\n")
+		emit("\t\t\t%s\n", onFillErr)
+		emit("\t\t}\n")
+	}
+}
+
 func generate_harnesses() {
 	// get pwd for subsequent commands
 	pwd, err := os.Getwd()
@@ -728,8 +830,32 @@ func generate_harnesses() {
 	}
 	for _, target_package := range Targets {
 		//fmt.Println(target_package.Name)
-		if !target_package.IsIncludable(TargetConfig) {
+		if reason, detail := target_package.ExcludeReason(TargetConfig); reason != "" {
 			//fmt.Println("Skipping package ", target_package.ImportPath)
+			for _, function := range target_package.TargetFunctions {
+				report_function(function, "", reason, detail)
+			}
+			for _, constructor := range target_package.TargetConstructors {
+				report_function(constructor, "", reason, detail)
+			}
+			continue
+		}
+		for _, constructor := range target_package.TargetConstructors {
+			if n := usesIgnoredType(constructor.TypesFunc, TargetConfig.IgnoreTypes); n != nil {
+				report_function(constructor, "", ReasonIgnoredType, n.String())
+				continue
+			}
+			if n := usesSubstitutedPackage(constructor.TypesFunc, TargetConfig.SubstitutePackages); n != nil {
+				report_function(constructor, "", ReasonSubstitutedType, n.String())
+				continue
+			}
+			report_function(constructor, "", ReasonConstructor, "")
+		}
+		if !target_package.IsIncludable(TargetConfig) {
+			for _, function := range target_package.TargetFunctions {
+				reason, detail := function.ExcludeReason(TargetConfig)
+				report_function(function, "", reason, detail)
+			}
 			continue
 		}
 
@@ -758,7 +884,7 @@ func generate_harnesses() {
 			fmt.Fprintln(os.Stderr, "nosy-neighbor: warning: continuing after failing to find abs path:", err)
 			abs = outFile
 		}
-		adjusted, err = imports.Process(abs, out, nil)
+		adjusted, err = fixImports(abs, out)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, "nosy-neighbor: warning: continuing after failing to automatically adjust imports:", err)
 			adjusted = out
@@ -779,13 +905,19 @@ func generate_harnesses() {
 		fmt.Println("nosy-neighbor: created", rel)
 	}
 
-	// write a list of all target fuzz functions
-	f, err := os.Create(fmt.Sprintf("%s/fuzzable.txt", pwd))
-	if err != nil {
+	// write the manifest of every harness generated
+	Manifest.Version = HarnessManifestVersion
+	Manifest.Target = TargetConfig.TargetRepo
+	Manifest.GeneratedAt = time.Now().UTC()
+	if err := Manifest.Save(filepath.Join(pwd, HarnessManifestFile)); err != nil {
 		log.Fatal(err)
 	}
-	for _, target := range FuzzTargets {
-		_, _ = f.WriteString(fmt.Sprintf("%s\n", target))
+
+	// and why every other function did not get one
+	Report.Version = SkipReportVersion
+	Report.Target = TargetConfig.TargetRepo
+	Report.GeneratedAt = Manifest.GeneratedAt
+	if err := Report.Save(filepath.Join(pwd, SkipReportFile)); err != nil {
+		log.Fatal(err)
 	}
-	f.Close()
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// version of the harness manifest format, this is a copy of the types in
+// nosy's src/types which reads the manifest back
+const HarnessManifestVersion = 1
+
+// name of the harness manifest in the target's repo root
+const HarnessManifestFile = "nosy_harnesses.json"
+
+// how a harness gets its arguments from the fuzzer
+const (
+	// the wrapper takes the arguments as native go test fuzz parameters
+	NativeSupport = "native"
+	// the wrapper decodes the arguments from a []byte with fill
+	FillSupport = "fill"
+)
+
+// Harness is one generated fuzz harness
+type Harness struct {
+	// Name is the name of the Fuzz_Nosy_ wrapper
+	Name string `json:"name"`
+	// Package is the import path of the package under test
+	Package   string `json:"package"`
+	Directory string `json:"directory"`
+	// File is the generated test file holding the wrapper
+	File     string `json:"file"`
+	Function string `json:"function"`
+	// Receiver is the receiver type of a wrapped method
+	Receiver  string `json:"receiver,omitempty"`
+	Signature string `json:"signature"`
+	// Constructor is the function the wrapper creates its receiver with
+	Constructor string `json:"constructor,omitempty"`
+	Support     string `json:"support"`
+}
+
+// HarnessManifest lists the harnesses of one generation run
+type HarnessManifest struct {
+	Version     int       `json:"version"`
+	Target      string    `json:"target"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Harnesses   []Harness `json:"harnesses"`
+}
+
+// Save writes the manifest to path, atomically so tooling never reads a
+// truncated file
+func (m *HarnessManifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".nosy_harnesses-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
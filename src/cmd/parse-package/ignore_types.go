@@ -0,0 +1,118 @@
+package main
+
+import (
+	"go/types"
+	"strings"
+)
+
+// matchesIgnoredType reports if the named type n is listed in ignore_types.
+// Entries name a type as Type, pkg.Type or import/path.Type, a leading * is
+// ignored.
+func matchesIgnoredType(n *types.Named, ignore_types []string) bool {
+	obj := n.Obj()
+	for _, entry := range ignore_types {
+		entry = strings.TrimPrefix(strings.TrimSpace(entry), "*")
+		if entry == obj.Name() {
+			return true
+		}
+		if obj.Pkg() == nil {
+			continue
+		}
+		if entry == obj.Pkg().Name()+"."+obj.Name() || entry == obj.Pkg().Path()+"."+obj.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredType returns the first type listed in ignore_types that t is or is
+// built from, or nil if there is none
+func ignoredType(t types.Type, ignore_types []string) *types.Named {
+	if len(ignore_types) == 0 {
+		return nil
+	}
+	return findNamedType(t, func(n *types.Named) bool {
+		return matchesIgnoredType(n, ignore_types)
+	})
+}
+
+// usesIgnoredType returns the ignored type the receiver or a parameter of f
+// uses, or nil if f uses none
+func usesIgnoredType(f *types.Func, ignore_types []string) *types.Named {
+	if len(ignore_types) == 0 {
+		return nil
+	}
+	return findNamedInputType(f, func(n *types.Named) bool {
+		return matchesIgnoredType(n, ignore_types)
+	})
+}
+
+// findNamedType returns the first named type matching match that t is or is
+// built from, through pointers, slices, arrays, maps, channels, function
+// signatures, anonymous structs and type arguments, or nil if there is none.
+// Named types are not looked into, a struct with a matching field is fine.
+func findNamedType(t types.Type, match func(n *types.Named) bool) *types.Named {
+	switch u := t.(type) {
+	case *types.Named:
+		if match(u) {
+			return u
+		}
+		args := u.TypeArgs()
+		for i := 0; i < args.Len(); i++ {
+			if n := findNamedType(args.At(i), match); n != nil {
+				return n
+			}
+		}
+	case *types.Pointer:
+		return findNamedType(u.Elem(), match)
+	case *types.Slice:
+		return findNamedType(u.Elem(), match)
+	case *types.Array:
+		return findNamedType(u.Elem(), match)
+	case *types.Chan:
+		return findNamedType(u.Elem(), match)
+	case *types.Map:
+		if n := findNamedType(u.Key(), match); n != nil {
+			return n
+		}
+		return findNamedType(u.Elem(), match)
+	case *types.Signature:
+		if n := findNamedTupleType(u.Params(), match); n != nil {
+			return n
+		}
+		return findNamedTupleType(u.Results(), match)
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if n := findNamedType(u.Field(i).Type(), match); n != nil {
+				return n
+			}
+		}
+	}
+	return nil
+}
+
+// findNamedTupleType returns the first matching named type among the
+// variables of a parameter or result list
+func findNamedTupleType(tuple *types.Tuple, match func(n *types.Named) bool) *types.Named {
+	for i := 0; i < tuple.Len(); i++ {
+		if n := findNamedType(tuple.At(i).Type(), match); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// findNamedInputType returns the first matching named type the receiver or a
+// parameter of f uses, the types a harness declares to call f
+func findNamedInputType(f *types.Func, match func(n *types.Named) bool) *types.Named {
+	sig, ok := f.Type().(*types.Signature)
+	if !ok {
+		return nil
+	}
+	if recv := sig.Recv(); recv != nil {
+		if n := findNamedType(recv.Type(), match); n != nil {
+			return n
+		}
+	}
+	return findNamedTupleType(sig.Params(), match)
+}
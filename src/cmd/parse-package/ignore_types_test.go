@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+const ignoreTypesDep = `package db
+
+type Conn struct{}
+
+type Row[T any] struct{ v T }
+`
+
+const ignoreTypesTarget = `package store
+
+import "example.com/db"
+
+type Store struct{ conn *db.Conn }
+
+func (s *Store) Get(key string) []byte { return nil }
+
+func Open(conn *db.Conn) *Store { return nil }
+
+func OpenAll(conns map[string][]*db.Conn) {}
+
+func Each(fn func(c db.Conn) error) {}
+
+func Scan(rows []db.Row[db.Conn]) {}
+
+func Wrap(w struct{ c *db.Conn }) {}
+
+func Close(s *Store) {}
+
+func Last() *db.Conn { return nil }
+`
+
+func TestUsesIgnoredType(t *testing.T) {
+	pkg := checkPackages(t, [2]string{"example.com/db", ignoreTypesDep}, [2]string{"example.com/store", ignoreTypesTarget})
+	tests := []struct {
+		function string
+		ignore   []string
+		want     string
+	}{
+		{"Open", []string{"Conn"}, "example.com/db.Conn"},
+		{"Open", []string{"db.Conn"}, "example.com/db.Conn"},
+		{"Open", []string{"example.com/db.Conn"}, "example.com/db.Conn"},
+		{"Open", []string{"*db.Conn"}, "example.com/db.Conn"},
+		{"Open", []string{"other.Conn"}, ""},
+		{"Open", nil, ""},
+		{"OpenAll", []string{"Conn"}, "example.com/db.Conn"},
+		{"Each", []string{"Conn"}, "example.com/db.Conn"},
+		{"Scan", []string{"Conn"}, "example.com/db.Conn"},
+		{"Scan", []string{"Row"}, "example.com/db.Row[example.com/db.Conn]"},
+		{"Wrap", []string{"Conn"}, "example.com/db.Conn"},
+		// the fields of a named type are not looked into
+		{"Close", []string{"Conn"}, ""},
+		{"Close", []string{"Store"}, "example.com/store.Store"},
+		{"Store.Get", []string{"store.Store"}, "example.com/store.Store"},
+		// results are not declared by the harness
+		{"Last", []string{"Conn"}, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if n := usesIgnoredType(lookupFunc(t, pkg, tt.function), tt.ignore); n != nil {
+			got = n.String()
+		}
+		if got != tt.want {
+			t.Errorf("usesIgnoredType(%s, %q) = %q, want %q", tt.function, tt.ignore, got, tt.want)
+		}
+	}
+}
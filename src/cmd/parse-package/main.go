@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"go/types"
 	"log"
+	"os"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -18,11 +19,23 @@ var TargetConfig TargetRepoConfig
 // global target list
 var Targets []TargetPackage
 
-var FuzzTargets []string
+// harnesses generated so far
+var Manifest HarnessManifest
+
+// what happened to every function found
+var Report SkipReport
 
 func main() {
-	// parse target config file
-	TargetConfig = ParseTargetConfig("/src/config.yaml")
+	// parse target config file, gen_harness.sh passes its path so the
+	// generator also works outside of the nosy-neighbor container
+	config_path := "/src/config.yaml"
+	if len(os.Args) > 1 {
+		config_path = os.Args[1]
+	}
+	TargetConfig = ParseTargetConfig(config_path)
+	if err := checkPatterns(TargetConfig); err != nil {
+		log.Fatal(err)
+	}
 
 	cfg := &packages.Config{Mode: packages.NeedName |
 		packages.NeedFiles |
@@ -66,5 +79,11 @@ func main() {
 		Targets = append(Targets, selected_pkg)
 	}
 
+	// "regress <crashes>.json" turns crashes into regression tests instead
+	// of generating harnesses
+	if len(os.Args) > 3 && os.Args[2] == "regress" {
+		generate_regression_tests(os.Args[3])
+		return
+	}
 	generate_harnesses()
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Entries of ignore_packages, include_packages, ignore_functions and
+// include_functions are patterns:
+//
+//	re:<regexp>  a regular expression that must match the whole name
+//	a glob       if it holds a * or ?, * matches any run of characters and
+//	             ? a single one, within one part of a function's name. A
+//	             function glob without a dot matches the last part too, so
+//	             Unmarshal* matches both Unmarshal and T.UnmarshalJSON
+//	a plain name any other entry, packages containing it and functions
+//	             named exactly by it match
+const regexpPrefix = "re:"
+
+// compiled patterns by entry and kind, every entry is checked by
+// checkPatterns before the first match
+var compiledPatterns = map[string]*regexp.Regexp{}
+
+// compilePattern turns a regexp or glob entry into a regexp matching whole
+// names. Package globs may span path elements, function globs stay within
+// one part of Package.Type.Function.
+func compilePattern(entry string, function bool) (*regexp.Regexp, error) {
+	if strings.HasPrefix(entry, regexpPrefix) {
+		return regexp.Compile("^(?:" + strings.TrimPrefix(entry, regexpPrefix) + ")$")
+	}
+	many, one := ".*", "."
+	if function {
+		many, one = "[^.]*", "[^.]"
+	}
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range entry {
+		switch r {
+		case '*':
+			expr.WriteString(many)
+		case '?':
+			expr.WriteString(one)
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// isPattern reports if entry is a regexp or glob rather than a plain name
+func isPattern(entry string) bool {
+	return strings.HasPrefix(entry, regexpPrefix) || strings.ContainsAny(entry, "*?")
+}
+
+// matchPattern reports if entry matches one of the names
+func matchPattern(entry string, function bool, names ...string) bool {
+	if !isPattern(entry) {
+		for _, name := range names {
+			if function && name == entry || !function && strings.Contains(name, entry) {
+				return true
+			}
+		}
+		return false
+	}
+	key := fmt.Sprintf("%t %s", function, entry)
+	re, ok := compiledPatterns[key]
+	if !ok {
+		var err error
+		if re, err = compilePattern(entry, function); err != nil {
+			return false
+		}
+		compiledPatterns[key] = re
+	}
+	// a function glob naming no type or package matches the function or
+	// method name on its own
+	last := function && !strings.HasPrefix(entry, regexpPrefix) && !strings.Contains(entry, ".")
+	for _, name := range names {
+		if re.MatchString(name) {
+			return true
+		}
+		if last && re.MatchString(name[strings.LastIndex(name, ".")+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny returns the first entry matching one of the names, or ""
+func matchAny(entries []string, function bool, names ...string) string {
+	for _, entry := range entries {
+		if matchPattern(entry, function, names...) {
+			return entry
+		}
+	}
+	return ""
+}
+
+// checkPatterns returns an error for the first pattern in the config that
+// does not compile
+func checkPatterns(target_config TargetRepoConfig) error {
+	lists := []struct {
+		key      string
+		entries  []string
+		function bool
+	}{
+		{"ignore_packages", target_config.IgnorePackages, false},
+		{"include_packages", target_config.IncludePackages, false},
+		{"ignore_functions", target_config.IgnoreFunctions, true},
+		{"include_functions", target_config.IncludeFunctions, true},
+	}
+	for _, list := range lists {
+		for _, entry := range list.entries {
+			if !isPattern(entry) {
+				continue
+			}
+			if _, err := compilePattern(entry, list.function); err != nil {
+				return fmt.Errorf("%s: %q: %w", list.key, entry, err)
+			}
+		}
+	}
+	return nil
+}
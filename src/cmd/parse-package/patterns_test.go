@@ -0,0 +1,95 @@
+package main
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    string
+		function bool
+		names    []string
+		want     bool
+	}{
+		// plain entries
+		{"package contains", "internal/mocks", false, []string{"example.com/x/internal/mocks/db"}, true},
+		{"package prefix of another", "example.com/x/cmd", false, []string{"example.com/x/cmdutil"}, true},
+		{"package not contained", "mocks", false, []string{"example.com/x/parse"}, false},
+		{"function exact", "Parse", true, []string{"Parse", "parse.Parse"}, true},
+		{"function qualified", "parse.Parse", true, []string{"Parse", "parse.Parse"}, true},
+		{"function not a substring", "Pars", true, []string{"Parse", "parse.Parse"}, false},
+		{"method qualified", "Conn.Close", true, []string{"Conn.Close", "net.Conn.Close"}, true},
+		{"method not by its bare name", "Close", true, []string{"Conn.Close", "net.Conn.Close"}, false},
+
+		// globs
+		{"package glob spans elements", "example.com/x/*/mocks", false, []string{"example.com/x/a/b/mocks"}, true},
+		{"package glob matches whole path", "*/mocks", false, []string{"example.com/x/mocks/db"}, false},
+		{"package single character", "example.com/x/v?", false, []string{"example.com/x/v2"}, true},
+		{"function glob", "Must*", true, []string{"MustParse", "parse.MustParse"}, true},
+		{"function glob within one part", "*.Close", true, []string{"Conn.Close", "net.Conn.Close"}, true},
+		{"function glob stops at dots", "parse.*", true, []string{"Conn.Close", "parse.Conn.Close"}, false},
+		{"method glob by its bare name", "Unmarshal*", true, []string{"T.UnmarshalJSON", "json.T.UnmarshalJSON"}, true},
+		{"method glob by its type", "*.Unmarshal*", true, []string{"T.UnmarshalJSON", "json.T.UnmarshalJSON"}, true},
+		{"method glob of another type", "U.Unmarshal*", true, []string{"T.UnmarshalJSON", "json.T.UnmarshalJSON"}, false},
+		{"package glob not by its last element", "mock*", false, []string{"example.com/x/mocks"}, false},
+		{"glob quotes the rest", "example.com/x*", false, []string{"exampleXcom/x1"}, false},
+
+		// regexps
+		{"regexp anchored subtree", `re:example\.com/x/cmd(/.*)?`, false, []string{"example.com/x/cmd/tool"}, true},
+		{"regexp anchored root", `re:example\.com/x/cmd(/.*)?`, false, []string{"example.com/x/cmd"}, true},
+		{"regexp anchored sibling", `re:example\.com/x/cmd(/.*)?`, false, []string{"example.com/x/cmdutil"}, false},
+		{"regexp matches whole name", "re:Parse", true, []string{"MustParse", "parse.MustParse"}, false},
+		{"regexp not by the method name", "re:Unmarshal.*", true, []string{"T.UnmarshalJSON", "json.T.UnmarshalJSON"}, false},
+		{"regexp alternation", "re:Get|Set", true, []string{"Set"}, true},
+		{"regexp alternation is anchored", "re:Get|Set", true, []string{"Setter"}, false},
+		{"regexp does not compile", "re:(", true, []string{"("}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchPattern(tt.entry, tt.function, tt.names...); got != tt.want {
+				t.Errorf("matchPattern(%q, %t, %q) = %t, want %t", tt.entry, tt.function, tt.names, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	entries := []string{"mocks", "re:.*/gen(/.*)?", "*_test"}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"example.com/x/internal/mocks", "mocks"},
+		{"example.com/x/gen/proto", "re:.*/gen(/.*)?"},
+		{"example.com/x/parse_test", "*_test"},
+		{"example.com/x/parse", ""},
+	}
+	for _, tt := range tests {
+		if got := matchAny(entries, false, tt.name); got != tt.want {
+			t.Errorf("matchAny(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPatterns(t *testing.T) {
+	tests := []struct {
+		name   string
+		config TargetRepoConfig
+		err    bool
+	}{
+		{"none", TargetRepoConfig{}, false},
+		{"valid", TargetRepoConfig{
+			IgnorePackages:   []string{"mocks", `re:example\.com/x/cmd(/.*)?`},
+			IncludeFunctions: []string{"Parse*", "re:Get|Set"},
+		}, false},
+		{"plain entries are not compiled", TargetRepoConfig{IgnoreFunctions: []string{"(("}}, false},
+		{"bad package regexp", TargetRepoConfig{IncludePackages: []string{"re:("}}, true},
+		{"bad function regexp", TargetRepoConfig{IgnoreFunctions: []string{"re:[a-"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkPatterns(tt.config); (err != nil) != tt.err {
+				t.Errorf("checkPatterns() = %v, want error %t", err, tt.err)
+			}
+		})
+	}
+}
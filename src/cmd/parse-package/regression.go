@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// regressionCrash is a crash to turn into a regression test, as listed by
+// "nosy regress"
+type regressionCrash struct {
+	// Bucket is the triage bucket of the crash, it names the test
+	Bucket  string `json:"bucket"`
+	Harness string `json:"harness"`
+	// Directory is the harness's package directory relative to the repo root
+	Directory string `json:"directory"`
+	// Input is the go test fuzz corpus file of the crash, relative to the
+	// crash list
+	Input string `json:"input"`
+	Panic string `json:"panic"`
+}
+
+// plannedHarness is a harness the generator would emit, with the package it
+// lives in
+type plannedHarness struct {
+	plan              *wrapperPlan
+	pkgName           string
+	harness_directory string
+}
+
+// planHarnesses plans the wrappers of every includable package without
+// emitting them, keyed by harness name and package directory
+func planHarnesses(pwd string) map[string]plannedHarness {
+	planned := map[string]plannedHarness{}
+	for _, target_package := range Targets {
+		if !target_package.IsIncludable(TargetConfig) {
+			continue
+		}
+		trimmed_real_path := strings.ReplaceAll(target_package.RealPath, TargetConfig.TargetModuleDeclaration, "")
+		harness_directory := pwd + trimmed_real_path
+		rel, err := filepath.Rel(pwd, harness_directory)
+		if err != nil {
+			continue
+		}
+
+		constructors := supportedConstructors(&target_package)
+		for _, function := range target_package.TargetFunctions {
+			plan, err := planWrapper(function, constructors, false)
+			if plan == nil || err != nil || plan.support == noSupport {
+				continue
+			}
+			planned[plan.wrapperName+"@"+rel] = plannedHarness{
+				plan:              plan,
+				pkgName:           target_package.Name,
+				harness_directory: harness_directory,
+			}
+		}
+	}
+	return planned
+}
+
+// corpusValues returns the values of a go test fuzz corpus file, which are
+// already Go expressions such as []byte("abc") or int(42)
+func corpusValues(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "go test fuzz v1" {
+		return nil, fmt.Errorf("%s is not a go test fuzz corpus file", path)
+	}
+	var values []string
+	for _, line := range lines[1:] {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+	return values, nil
+}
+
+// decodeFilledArgs decodes the data []byte input of a fill based harness
+// into Go literals of its arguments. It fills the arguments exactly like the
+// harness does in a throwaway test inside the package and prints them.
+func decodeFilledArgs(h plannedHarness, data string, crash regressionCrash) ([]string, error) {
+	out, err := os.CreateTemp("", "nosy-decode-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+
+	buf := new(bytes.Buffer)
+	emit := func(format string, args ...interface{}) {
+		fmt.Fprintf(buf, format, args...)
+	}
+	emit("package %s\n\n", h.pkgName)
+	emit("import (\n")
+	for _, pkg := range []string{"math", "os", "reflect", "sort", "strconv", "strings", "testing"} {
+		emit("\t%q\n", pkg)
+	}
+	emit(")\n\n")
+	emit("func TestNosyDecode(t *testing.T) {\n")
+	emit("\t\tdata := %s\n", data)
+	emitFill(emit, h.plan, "t.Fatal(fill_err)")
+	emit("\t\tvar out []string\n")
+	for _, p := range h.plan.paramReprs {
+		emit("\t\tout = append(out, nosyLiteral(reflect.ValueOf(&%s).Elem(), %q, true))\n", p.argName, h.pkgName)
+	}
+	emit("\t\tif err := os.WriteFile(%q, []byte(nosyJoin(out)), 0o644); err != nil {\n", out.Name())
+	emit("\t\t\tt.Fatal(err)\n")
+	emit("\t\t}\n")
+	emit("}\n")
+	emit(literalPrinter)
+
+	decode_file := filepath.Join(h.harness_directory, fmt.Sprintf("nosy_decode_%s_test.go", crash.Bucket))
+	if err := os.WriteFile(decode_file, buf.Bytes(), 0o644); err != nil {
+		return nil, err
+	}
+	defer os.Remove(decode_file)
+
+	cmd := exec.Command(TargetConfig.TargetGoVersion, "test", "-count=1", "-run=^TestNosyDecode$", ".")
+	cmd.Dir = h.harness_directory
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("decoding the input of %s failed: %w\n%s", crash.Harness, err, output)
+	}
+	literals, err := os.ReadFile(out.Name())
+	if err != nil {
+		return nil, err
+	}
+	values := strings.Split(string(literals), literalSeparator)
+	if len(values) != len(h.plan.paramReprs) {
+		return nil, fmt.Errorf("decoding the input of %s returned %d of %d arguments", crash.Harness, len(values), len(h.plan.paramReprs))
+	}
+	return values, nil
+}
+
+// emitRegressionTest emits a test that calls the function under test with
+// the arguments of a crash hard-coded
+func emitRegressionTest(h plannedHarness, crash regressionCrash, literals []string) []byte {
+	plan := h.plan
+	buf := new(bytes.Buffer)
+	emit := func(format string, args ...interface{}) {
+		fmt.Fprintf(buf, format, args...)
+	}
+
+	emit("package %s\n\n", h.pkgName)
+	emit("import \"testing\"\n\n")
+	testName := fmt.Sprintf("TestNosyRegression_%s", crash.Bucket)
+	emit("// %s reproduces a crash found by fuzzing %s with nosy:\n", testName, crash.Harness)
+	emit("//\n")
+	for _, line := range strings.Split(crash.Panic, "\n") {
+		emit("//\t%s\n", line)
+	}
+	emit("//\n")
+	emit("// It fails until the bug is fixed.\n")
+	emit("func %s(t *testing.T) {\n", testName)
+	for i, p := range plan.paramReprs {
+		if plan.support == nativeSupport {
+			emit("\t%s := %s\n", p.argName, literals[i])
+		} else {
+			emit("\tvar %s %s = %s\n", p.argName, p.argType, literals[i])
+		}
+	}
+	emitCall(emit, plan, "t.Fatal(err)")
+	emit("}\n")
+
+	uses := func(helper string) bool {
+		for _, literal := range literals {
+			if strings.Contains(literal, helper+"(") {
+				return true
+			}
+		}
+		return false
+	}
+	if uses("nosyPtr") {
+		emit("\n// nosyPtr_%s returns a pointer to a copy of v\n", crash.Bucket)
+		emit("func nosyPtr_%s[T any](v T) *T {\n\treturn &v\n}\n", crash.Bucket)
+	}
+	return bytes.ReplaceAll(buf.Bytes(), []byte("nosyPtr("), []byte(fmt.Sprintf("nosyPtr_%s(", crash.Bucket)))
+}
+
+// generate_regression_tests writes a regression test for every crash listed
+// in crashes_path into the package of the crash's harness
+func generate_regression_tests(crashes_path string) {
+	// get pwd for subsequent commands
+	pwd, err := os.Getwd()
+	if err != nil {
+		fail(err)
+	}
+	data, err := os.ReadFile(crashes_path)
+	if err != nil {
+		fail(err)
+	}
+	var crashes []regressionCrash
+	if err := json.Unmarshal(data, &crashes); err != nil {
+		fail(fmt.Errorf("%s: %w", crashes_path, err))
+	}
+
+	planned := planHarnesses(pwd)
+	failed := 0
+	for _, crash := range crashes {
+		out, err := generate_regression_test(planned, crash, filepath.Dir(crashes_path))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "nosy-neighbor: warning: no regression test for %s: %v\n", crash.Bucket, err)
+			failed++
+			continue
+		}
+		rel, err := filepath.Rel(pwd, out)
+		if err != nil {
+			rel = out
+		}
+		fmt.Println("nosy-neighbor: created", rel)
+	}
+	if failed > 0 {
+		fail(fmt.Errorf("%d of %d regression tests could not be generated", failed, len(crashes)))
+	}
+}
+
+// generate_regression_test writes the regression test for one crash and
+// returns its path
+func generate_regression_test(planned map[string]plannedHarness, crash regressionCrash, crashes_dir string) (string, error) {
+	h, ok := planned[crash.Harness+"@"+filepath.Clean(crash.Directory)]
+	if !ok {
+		return "", fmt.Errorf("harness %s is not generated for %s anymore", crash.Harness, crash.Directory)
+	}
+	input := crash.Input
+	if !filepath.IsAbs(input) {
+		input = filepath.Join(crashes_dir, input)
+	}
+	values, err := corpusValues(input)
+	if err != nil {
+		return "", err
+	}
+
+	// an earlier test for the crash would be compiled into the decode test
+	outFile := filepath.Join(h.harness_directory, fmt.Sprintf("Nosy_regression_%s_test.go", crash.Bucket))
+	if err := os.Remove(outFile); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	literals := values
+	if h.plan.support == fillRequired {
+		if len(values) != 1 {
+			return "", fmt.Errorf("%s takes data []byte, the input has %d values", crash.Harness, len(values))
+		}
+		literals, err = decodeFilledArgs(h, values[0], crash)
+		if err != nil {
+			return "", err
+		}
+	} else if len(values) != len(h.plan.paramReprs) {
+		return "", fmt.Errorf("%s takes %d arguments, the input has %d values", crash.Harness, len(h.plan.paramReprs), len(values))
+	}
+
+	out := emitRegressionTest(h, crash, literals)
+	adjusted, err := fixImports(outFile, out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "nosy-neighbor: warning: continuing after failing to automatically adjust imports:", err)
+		adjusted = out
+	}
+	if err := os.WriteFile(outFile, adjusted, 0o644); err != nil {
+		return "", err
+	}
+	return outFile, nil
+}
+
+// literalSeparator separates the arguments printed by the decode test
+const literalSeparator = "\n\x00\n"
+
+// literalPrinter is compiled into the decode test. nosyLiteral prints a
+// value as a Go expression that evaluates to it inside package pkg. Untyped
+// expressions are only used where typed is false, i.e. where the type is
+// clear from the context such as the elements of a composite literal.
+const literalPrinter = `
+func nosyJoin(values []string) string {
+	return strings.Join(values, "\n\x00\n")
+}
+
+func nosyType(t reflect.Type, pkg string) string {
+	typ := strings.ReplaceAll(t.String(), pkg+".", "")
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Func, reflect.Chan:
+		// (*T)(nil), not *T(nil)
+		return "(" + typ + ")"
+	}
+	return typ
+}
+
+func nosyLiteral(v reflect.Value, pkg string, typed bool) string {
+	t := v.Type()
+	typ := nosyType(t, pkg)
+	wrap := func(s string) string {
+		if typed || t.Name() != t.Kind().String() {
+			return typ + "(" + s + ")"
+		}
+		return s
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return wrap(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return wrap(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return wrap(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch {
+		case math.IsNaN(f):
+			return typ + "(math.NaN())"
+		case math.IsInf(f, 1):
+			return typ + "(math.Inf(1))"
+		case math.IsInf(f, -1):
+			return typ + "(math.Inf(-1))"
+		}
+		return typ + "(" + strconv.FormatFloat(f, 'g', -1, 64) + ")"
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return typ + "(complex(" + strconv.FormatFloat(real(c), 'g', -1, 64) + ", " + strconv.FormatFloat(imag(c), 'g', -1, 64) + "))"
+	case reflect.String:
+		return wrap(strconv.Quote(v.String()))
+	case reflect.Slice:
+		if v.IsNil() {
+			return typ + "(nil)"
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return typ + "(" + strconv.Quote(string(v.Bytes())) + ")"
+		}
+		fallthrough
+	case reflect.Array:
+		var elems []string
+		for i := 0; i < v.Len(); i++ {
+			elems = append(elems, nosyLiteral(v.Index(i), pkg, false))
+		}
+		return typ + "{" + strings.Join(elems, ", ") + "}"
+	case reflect.Map:
+		if v.IsNil() {
+			return typ + "(nil)"
+		}
+		var elems []string
+		for _, k := range v.MapKeys() {
+			elems = append(elems, nosyLiteral(k, pkg, false)+": "+nosyLiteral(v.MapIndex(k), pkg, false))
+		}
+		sort.Strings(elems)
+		return typ + "{" + strings.Join(elems, ", ") + "}"
+	case reflect.Struct:
+		var fields []string
+		for i := 0; i < v.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && strings.Contains(typ, ".") {
+				// unexported fields of other packages cannot be set
+				continue
+			}
+			fields = append(fields, f.Name+": "+nosyLiteral(v.Field(i), pkg, false))
+		}
+		return typ + "{" + strings.Join(fields, ", ") + "}"
+	case reflect.Ptr:
+		if v.IsNil() {
+			return typ + "(nil)"
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			return "&" + nosyLiteral(v.Elem(), pkg, true)
+		}
+		return "nosyPtr(" + nosyLiteral(v.Elem(), pkg, true) + ")"
+	case reflect.Interface:
+		if v.IsNil() {
+			return typ + "(nil)"
+		}
+		return nosyLiteral(v.Elem(), pkg, true)
+	default:
+		// funcs, channels and unsafe pointers are filled with nil
+		return typ + "(nil)"
+	}
+}
+`
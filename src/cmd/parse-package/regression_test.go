@@ -0,0 +1,167 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCorpusValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		corpus string
+		want   []string
+		err    bool
+	}{
+		{"native", "go test fuzz v1\nint(42)\n[]byte(\"abc\")\n", []string{"int(42)", `[]byte("abc")`}, false},
+		{"blank lines", "go test fuzz v1\n\nstring(\"a\")\n\n", []string{`string("a")`}, false},
+		{"no values", "go test fuzz v1\n", nil, false},
+		{"not a corpus file", "int(42)\n", nil, true},
+		{"empty", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "crash")
+			if err := os.WriteFile(path, []byte(tt.corpus), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := corpusValues(path)
+			if (err != nil) != tt.err {
+				t.Fatalf("corpusValues() error %v, want error %t", err, tt.err)
+			}
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("corpusValues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+const regressionTarget = `package calc
+
+func Sum(a, b int) int { return a + b }
+
+func Scale(p *int, factor float64) {}
+`
+
+// planRegression plans the harness of a function of regressionTarget, its
+// package directory is dir
+func planRegression(t *testing.T, function, dir string) plannedHarness {
+	t.Helper()
+	pkg := checkPackages(t, [2]string{"example.com/calc", regressionTarget})
+	plan, err := planWrapper(TargetFunction{Name: function, PackageName: "calc", TypesFunc: lookupFunc(t, pkg, function)}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plannedHarness{plan: plan, pkgName: "calc", harness_directory: dir}
+}
+
+func TestEmitRegressionTest(t *testing.T) {
+	tests := []struct {
+		function string
+		literals []string
+		want     []string
+		helper   bool
+	}{
+		{
+			function: "Sum",
+			literals: []string{"int(1)", "int(-1)"},
+			want:     []string{"\ta := int(1)\n", "\tb := int(-1)\n", "Sum(a, b)"},
+		},
+		{
+			function: "Scale",
+			literals: []string{"(*int)(nil)", "float64(0.5)"},
+			want:     []string{"\tvar p *int = (*int)(nil)\n", "\tvar factor float64 = float64(0.5)\n", "Scale(p, factor)"},
+		},
+		{
+			function: "Scale",
+			literals: []string{"nosyPtr(int(3))", "float64(1)"},
+			want:     []string{"\tvar p *int = nosyPtr_6c6af616619d7c83(int(3))\n", "Scale(p, factor)"},
+			helper:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			crash := regressionCrash{
+				Bucket:  "6c6af616619d7c83",
+				Harness: "Fuzz_Nosy_" + tt.function + "__",
+				Panic:   "runtime error: integer divide by zero\ngoroutine 1 [running]:",
+			}
+			out := string(emitRegressionTest(planRegression(t, tt.function, t.TempDir()), crash, tt.literals))
+			if _, err := parser.ParseFile(token.NewFileSet(), "Nosy_regression_test.go", out, 0); err != nil {
+				t.Fatalf("emitted test does not parse: %v\n%s", err, out)
+			}
+			want := append([]string{
+				"func TestNosyRegression_6c6af616619d7c83(t *testing.T) {\n",
+				"//\truntime error: integer divide by zero\n//\tgoroutine 1 [running]:\n",
+			}, tt.want...)
+			for _, s := range want {
+				if !strings.Contains(out, s) {
+					t.Errorf("emitted test lacks %q:\n%s", s, out)
+				}
+			}
+			if strings.Contains(out, "func nosyPtr_6c6af616619d7c83[") != tt.helper {
+				t.Errorf("emitted test declares nosyPtr %t, want %t:\n%s", !tt.helper, tt.helper, out)
+			}
+		})
+	}
+}
+
+func TestGenerateRegressionTest(t *testing.T) {
+	dir := t.TempDir()
+	crashes_dir := filepath.Join(dir, "crashes")
+	if err := os.MkdirAll(crashes_dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, corpus := range map[string]string{
+		"sum":   "go test fuzz v1\nint(1)\nint(-1)\n",
+		"short": "go test fuzz v1\nint(1)\n",
+		"bad":   "int(1)\n",
+	} {
+		if err := os.WriteFile(filepath.Join(crashes_dir, name), []byte(corpus), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	planned := map[string]plannedHarness{
+		"Fuzz_Nosy_Sum__@calc": planRegression(t, "Sum", dir),
+	}
+
+	tests := []struct {
+		name  string
+		crash regressionCrash
+		err   string
+	}{
+		{"generated", regressionCrash{Bucket: "b1", Harness: "Fuzz_Nosy_Sum__", Directory: "calc/", Input: "sum"}, ""},
+		{"absolute input", regressionCrash{Bucket: "b2", Harness: "Fuzz_Nosy_Sum__", Directory: "calc", Input: filepath.Join(crashes_dir, "sum")}, ""},
+		{"harness gone", regressionCrash{Bucket: "b3", Harness: "Fuzz_Nosy_Diff__", Directory: "calc", Input: "sum"}, "is not generated"},
+		{"other package", regressionCrash{Bucket: "b4", Harness: "Fuzz_Nosy_Sum__", Directory: "other", Input: "sum"}, "is not generated"},
+		{"missing argument", regressionCrash{Bucket: "b5", Harness: "Fuzz_Nosy_Sum__", Directory: "calc", Input: "short"}, "takes 2 arguments, the input has 1"},
+		{"not a corpus file", regressionCrash{Bucket: "b6", Harness: "Fuzz_Nosy_Sum__", Directory: "calc", Input: "bad"}, "not a go test fuzz corpus file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := generate_regression_test(planned, tt.crash, crashes_dir)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("generate_regression_test() = %q, %v, want an error containing %q", out, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(dir, "Nosy_regression_"+tt.crash.Bucket+"_test.go"); out != want {
+				t.Errorf("generate_regression_test() = %s, want %s", out, want)
+			}
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), "\ta := int(1)\n\tb := int(-1)\n") {
+				t.Errorf("regression test lacks the crash's arguments:\n%s", data)
+			}
+		})
+	}
+}
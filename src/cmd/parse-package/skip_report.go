@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// version of the skip report format, this is a copy of the types in nosy's
+// src/types which reads the report back
+const SkipReportVersion = 1
+
+// name of the skip report in the target's repo root
+const SkipReportFile = "nosy_skip_report.json"
+
+// why a discovered function did or did not get a harness
+const (
+	// a harness was generated for the function
+	ReasonHarness = "harness"
+	// the function's package matches ignore_packages
+	ReasonIgnoredPackage = "ignored_package"
+	// include_packages is set and does not match the function's package
+	ReasonNotIncludedPackage = "not_included_package"
+	// the function matches ignore_functions
+	ReasonIgnoredFunction = "ignored_function"
+	// include_functions is set and does not match the function
+	ReasonNotIncludedFunction = "not_included_function"
+	// the receiver or a parameter uses a type in ignore_types
+	ReasonIgnoredType = "ignored_type"
+	// the receiver or a parameter uses a type of a package in
+	// substitute_packages, which the substitute cannot stand in for
+	ReasonSubstitutedType = "substituted_type"
+	// the function is a method declared by an interface
+	ReasonInterfaceMethod = "interface_method"
+	// the function builds the receivers of methods and is not wrapped itself
+	ReasonConstructor = "constructor"
+	// the function has no receiver and no parameters
+	ReasonNoInputs = "no_inputs"
+	// a parameter is a func the fuzzer cannot produce
+	ReasonFuncParam = "func_param"
+	// a parameter is a channel the fuzzer cannot produce
+	ReasonChanParam = "chan_param"
+	// a parameter is an interface fill has no implementation for
+	ReasonInterfaceParam = "interface_param"
+	// the receiver is neither a named type nor a pointer to one
+	ReasonUnknownReceiver = "unknown_receiver"
+	// planning the wrapper failed in another way, see the detail
+	ReasonError = "error"
+)
+
+// ReportedFunction is one function found in the target and what the
+// generator did with it
+type ReportedFunction struct {
+	// Package is the import path of the function's package
+	Package  string `json:"package"`
+	Function string `json:"function"`
+	// Receiver is the receiver type of a method
+	Receiver  string `json:"receiver,omitempty"`
+	Signature string `json:"signature"`
+	// Harness is the wrapper generated for the function, empty if skipped
+	Harness string `json:"harness,omitempty"`
+	Reason  string `json:"reason"`
+	// Detail is the offending parameter type, config entry or error of a
+	// skip
+	Detail string `json:"detail,omitempty"`
+}
+
+// SkipReport lists every function of one generation run and the reason each
+// did or did not get a harness
+type SkipReport struct {
+	Version     int                `json:"version"`
+	Target      string             `json:"target"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Totals      map[string]int     `json:"totals"`
+	Functions   []ReportedFunction `json:"functions"`
+}
+
+// skipError is returned when a function is not wrapped for a reason the skip
+// report lists
+type skipError struct {
+	reason string
+	detail string
+}
+
+func (e *skipError) Error() string {
+	if e.detail == "" {
+		return e.reason
+	}
+	return e.reason + ": " + e.detail
+}
+
+// report_function adds function to the skip report
+func report_function(function TargetFunction, harness string, reason string, detail string) {
+	f := ReportedFunction{
+		Package:  function.PackagePath,
+		Function: function.Name,
+		Harness:  harness,
+		Reason:   reason,
+		Detail:   detail,
+	}
+	if function.TypesFunc != nil {
+		f.Signature = types.ObjectString(function.TypesFunc, types.RelativeTo(function.TypesFunc.Pkg()))
+		if n := receiver(function.TypesFunc); n != nil {
+			f.Receiver = n.Obj().Name()
+		}
+	}
+	Report.Functions = append(Report.Functions, f)
+}
+
+// Save sorts the functions, counts them per reason and writes the report to
+// path, atomically so tooling never reads a truncated file
+func (r *SkipReport) Save(path string) error {
+	sort.SliceStable(r.Functions, func(i, j int) bool {
+		a, b := r.Functions[i], r.Functions[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Receiver != b.Receiver {
+			return a.Receiver < b.Receiver
+		}
+		return a.Function < b.Function
+	})
+	r.Totals = map[string]int{}
+	for _, f := range r.Functions {
+		r.Totals[f.Reason]++
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".nosy_skip_report-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
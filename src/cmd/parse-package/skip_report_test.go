@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSkipError(t *testing.T) {
+	tests := []struct {
+		err  *skipError
+		want string
+	}{
+		{&skipError{ReasonNoInputs, ""}, "no_inputs"},
+		{&skipError{ReasonFuncParam, "func(int) error"}, "func_param: func(int) error"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+const skipReportTarget = `package parse
+
+type Point struct{ x, y int }
+
+func (p *Point) Div(d int) int { return p.x / d }
+
+func Sum(a, b int) int { return a + b }
+`
+
+func TestReportFunction(t *testing.T) {
+	defer func(r SkipReport) { Report = r }(Report)
+	pkg := checkPackages(t, [2]string{"example.com/fake/parse", skipReportTarget})
+	tests := []struct {
+		function TargetFunction
+		want     ReportedFunction
+	}{
+		{
+			function: TargetFunction{Name: "Sum", PackagePath: "example.com/fake/parse", TypesFunc: lookupFunc(t, pkg, "Sum")},
+			want: ReportedFunction{
+				Package:   "example.com/fake/parse",
+				Function:  "Sum",
+				Signature: "func Sum(a int, b int) int",
+				Harness:   "Fuzz_Nosy_Sum__",
+				Reason:    ReasonHarness,
+			},
+		},
+		{
+			function: TargetFunction{Name: "Div", PackagePath: "example.com/fake/parse", TypesFunc: lookupFunc(t, pkg, "Point.Div")},
+			want: ReportedFunction{
+				Package:   "example.com/fake/parse",
+				Function:  "Div",
+				Receiver:  "Point",
+				Signature: "func (*Point).Div(d int) int",
+				Reason:    ReasonIgnoredType,
+				Detail:    "example.com/fake/parse.Point",
+			},
+		},
+		{
+			// functions excluded before type checking have no signature
+			function: TargetFunction{Name: "Sum", PackagePath: "example.com/fake/vendor/parse"},
+			want: ReportedFunction{
+				Package:  "example.com/fake/vendor/parse",
+				Function: "Sum",
+				Reason:   ReasonIgnoredPackage,
+				Detail:   "vendor",
+			},
+		},
+	}
+	for _, tt := range tests {
+		Report = SkipReport{}
+		report_function(tt.function, tt.want.Harness, tt.want.Reason, tt.want.Detail)
+		if len(Report.Functions) != 1 || Report.Functions[0] != tt.want {
+			t.Errorf("report_function(%s) reported %+v, want %+v", tt.function.Name, Report.Functions, tt.want)
+		}
+	}
+}
+
+func TestSkipReportSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SkipReportFile)
+	r := &SkipReport{
+		Version: SkipReportVersion,
+		Target:  "fake",
+		Functions: []ReportedFunction{
+			{Package: "example.com/fake/parse", Function: "Sum", Reason: ReasonHarness},
+			{Package: "example.com/fake/other", Function: "Header", Reason: ReasonHarness},
+			{Package: "example.com/fake/parse", Function: "Div", Receiver: "Point", Reason: ReasonNoInputs},
+			{Package: "example.com/fake/parse", Function: "Add", Receiver: "Point", Reason: ReasonHarness},
+			{Package: "example.com/fake/parse", Function: "Each", Reason: ReasonFuncParam},
+		},
+	}
+	if err := r.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := &SkipReport{}
+	if err := json.Unmarshal(data, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	for _, f := range saved.Functions {
+		name := f.Package + " " + f.Function
+		if f.Receiver != "" {
+			name = f.Package + " " + f.Receiver + "." + f.Function
+		}
+		order = append(order, name)
+	}
+	want := []string{
+		"example.com/fake/other Header",
+		"example.com/fake/parse Each",
+		"example.com/fake/parse Sum",
+		"example.com/fake/parse Point.Add",
+		"example.com/fake/parse Point.Div",
+	}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("saved functions in the order %q, want %q", order, want)
+	}
+	totals := map[string]int{ReasonHarness: 3, ReasonNoInputs: 1, ReasonFuncParam: 1}
+	if !reflect.DeepEqual(saved.Totals, totals) {
+		t.Errorf("saved totals %v, want %v", saved.Totals, totals)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("report directory holds %d files, want only %s", len(entries), SkipReportFile)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/imports"
+)
+
+// fixImports adds and removes the imports of a generated file, then swaps
+// the packages listed in substitute_packages for their substitutes
+func fixImports(filename string, src []byte) ([]byte, error) {
+	adjusted, err := imports.Process(filename, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	return substitutePackages(filename, adjusted, TargetConfig.SubstitutePackages)
+}
+
+// substitutePackages rewrites the imports of src that are keys of
+// substitutes to their values. The rewritten imports keep the name the file
+// refers to them by, so the substitute must provide the same identifiers.
+func substitutePackages(filename string, src []byte, substitutes map[string]string) ([]byte, error) {
+	if len(substitutes) == 0 {
+		return src, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	for _, spec := range file.Imports {
+		old_path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		new_path, ok := substitutes[old_path]
+		if !ok || new_path == old_path {
+			continue
+		}
+		if spec.Name == nil && assumedPackageName(new_path) != assumedPackageName(old_path) {
+			spec.Name = ast.NewIdent(assumedPackageName(old_path))
+		}
+		spec.Path.Value = strconv.Quote(new_path)
+		changed = true
+	}
+	if !changed {
+		return src, nil
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// usesSubstitutedPackage returns the first type of a package listed in
+// substitute_packages that the receiver or a parameter of f uses, or nil if
+// f uses none. The harness would declare it from the substitute, whose type
+// f does not accept, so the harness would not build.
+func usesSubstitutedPackage(f *types.Func, substitutes map[string]string) *types.Named {
+	if len(substitutes) == 0 {
+		return nil
+	}
+	return findNamedInputType(f, func(n *types.Named) bool {
+		pkg := n.Obj().Pkg()
+		if pkg == nil {
+			return false
+		}
+		new_path, ok := substitutes[pkg.Path()]
+		return ok && new_path != pkg.Path()
+	})
+}
+
+// assumedPackageName returns the package name goimports assumes for an
+// import path, which is the name generated files refer to it by when the
+// import is not named: the last path element without a major version
+// suffix, a "go-" prefix or anything after the first non identifier rune
+func assumedPackageName(import_path string) string {
+	base := path.Base(import_path)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			dir := path.Dir(import_path)
+			if dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// mapImporter imports the packages type checked by a test
+type mapImporter map[string]*types.Package
+
+func (m mapImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := m[path]; ok {
+		return pkg, nil
+	}
+	return nil, fmt.Errorf("package %s is not part of the test", path)
+}
+
+// checkPackages type checks the sources, by import path, in order, each
+// may import those before it. It returns the last package.
+func checkPackages(t *testing.T, sources ...[2]string) *types.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	imported := mapImporter{}
+	var pkg *types.Package
+	for _, source := range sources {
+		file, err := parser.ParseFile(fset, source[0]+".go", source[1], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config := types.Config{Importer: imported}
+		pkg, err = config.Check(source[0], fset, []*ast.File{file}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imported[source[0]] = pkg
+	}
+	return pkg
+}
+
+// lookupFunc returns the function, or the method as Type.Method, of pkg
+func lookupFunc(t *testing.T, pkg *types.Package, name string) *types.Func {
+	t.Helper()
+	typ, method, ok := strings.Cut(name, ".")
+	if !ok {
+		f, ok := pkg.Scope().Lookup(name).(*types.Func)
+		if !ok {
+			t.Fatalf("no function %s in %s", name, pkg.Path())
+		}
+		return f
+	}
+	named := pkg.Scope().Lookup(typ).Type().(*types.Named)
+	for i := 0; i < named.NumMethods(); i++ {
+		if named.Method(i).Name() == method {
+			return named.Method(i)
+		}
+	}
+	t.Fatalf("no method %s in %s", name, pkg.Path())
+	return nil
+}
+
+const substituteDep = `package crypto
+
+type Key [32]byte
+
+type Signer struct{ key Key }
+`
+
+const substituteTarget = `package sign
+
+import "example.com/crypto"
+
+type Message []byte
+
+func Verify(msg Message, sig []byte) bool { return len(msg) == len(sig) }
+
+func Sign(key crypto.Key, msg Message) []byte { return msg }
+
+func SignAll(keys map[string]*crypto.Key, msg Message) []byte { return msg }
+
+func KeyOf(msg Message) crypto.Key { return crypto.Key{} }
+
+type Box struct{ signer crypto.Signer }
+
+func (b *Box) Seal(msg Message) []byte { return msg }
+
+type Sealer crypto.Signer
+
+func (s Sealer) Seal(msg Message) []byte { return msg }
+`
+
+func TestUsesSubstitutedPackage(t *testing.T) {
+	pkg := checkPackages(t, [2]string{"example.com/crypto", substituteDep}, [2]string{"example.com/sign", substituteTarget})
+	substitutes := map[string]string{"example.com/crypto": "example.com/crypto/fake"}
+	tests := []struct {
+		function    string
+		substitutes map[string]string
+		want        string
+	}{
+		{"Verify", substitutes, ""},
+		{"Sign", substitutes, "example.com/crypto.Key"},
+		{"SignAll", substitutes, "example.com/crypto.Key"},
+		// results are not declared by the harness
+		{"KeyOf", substitutes, ""},
+		// named types are not looked into
+		{"Box.Seal", substitutes, ""},
+		{"Sealer.Seal", substitutes, ""},
+		{"Sign", nil, ""},
+		{"Sign", map[string]string{"example.com/crypto": "example.com/crypto"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			got := ""
+			if n := usesSubstitutedPackage(lookupFunc(t, pkg, tt.function), tt.substitutes); n != nil {
+				got = n.String()
+			}
+			if got != tt.want {
+				t.Errorf("usesSubstitutedPackage(%s) = %q, want %q", tt.function, got, tt.want)
+			}
+		})
+	}
+}
+
+// a function taking a type of a substituted package is reported as such
+func TestExcludeSubstitutedType(t *testing.T) {
+	pkg := checkPackages(t, [2]string{"example.com/crypto", substituteDep}, [2]string{"example.com/sign", substituteTarget})
+	config := TargetRepoConfig{SubstitutePackages: map[string]string{"example.com/crypto": "example.com/crypto/fake"}}
+	f := TargetFunction{Name: "Sign", PackageName: "sign", TypesFunc: lookupFunc(t, pkg, "Sign")}
+	reason, detail := f.ExcludeReason(config)
+	if reason != ReasonSubstitutedType || detail != "example.com/crypto.Key" {
+		t.Errorf("ExcludeReason() = %s, %s, want %s, example.com/crypto.Key", reason, detail, ReasonSubstitutedType)
+	}
+}
+
+func TestSubstitutePackages(t *testing.T) {
+	const harness = `package sign
+
+import (
+	"testing"
+
+	"example.com/crypto"
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+func Fuzz_Nosy_Sign__(f *testing.F) {}
+`
+	tests := []struct {
+		name        string
+		substitutes map[string]string
+		imports     []string
+	}{
+		{
+			name:    "none",
+			imports: []string{`"testing"`, `"example.com/crypto"`, `blst "github.com/supranational/blst/bindings/go"`},
+		},
+		{
+			name:        "same name",
+			substitutes: map[string]string{"example.com/crypto": "example.com/fake/crypto"},
+			imports:     []string{`"testing"`, `"example.com/fake/crypto"`, `blst "github.com/supranational/blst/bindings/go"`},
+		},
+		{
+			name:        "other name keeps the old one",
+			substitutes: map[string]string{"example.com/crypto": "example.com/crypto/fakecrypto"},
+			imports:     []string{`"testing"`, `crypto "example.com/crypto/fakecrypto"`, `blst "github.com/supranational/blst/bindings/go"`},
+		},
+		{
+			name:        "named import",
+			substitutes: map[string]string{"github.com/supranational/blst/bindings/go": "example.com/blst/fake"},
+			imports:     []string{`"testing"`, `"example.com/crypto"`, `blst "example.com/blst/fake"`},
+		},
+		{
+			name:        "not imported",
+			substitutes: map[string]string{"example.com/other": "example.com/fake/other"},
+			imports:     []string{`"testing"`, `"example.com/crypto"`, `blst "github.com/supranational/blst/bindings/go"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := substitutePackages("Fuzz_Nosy_test.go", []byte(harness), tt.substitutes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, err := parser.ParseFile(token.NewFileSet(), "Fuzz_Nosy_test.go", out, parser.ImportsOnly)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var imports []string
+			for _, spec := range file.Imports {
+				imp := spec.Path.Value
+				if spec.Name != nil {
+					imp = spec.Name.Name + " " + imp
+				}
+				imports = append(imports, imp)
+			}
+			// formatting sorts the imports of a block
+			sort.Strings(imports)
+			sort.Strings(tt.imports)
+			if strings.Join(imports, "; ") != strings.Join(tt.imports, "; ") {
+				t.Errorf("imports %q, want %q", imports, tt.imports)
+			}
+		})
+	}
+}
+
+func TestAssumedPackageName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"example.com/crypto", "crypto"},
+		{"gopkg.in/yaml.v2", "yaml"},
+		{"github.com/x/go-cmp", "cmp"},
+		{"github.com/x/proto/v2", "proto"},
+		{"github.com/supranational/blst/bindings/go", "go"},
+		{"v2", "v2"},
+	}
+	for _, tt := range tests {
+		if got := assumedPackageName(tt.path); got != tt.want {
+			t.Errorf("assumedPackageName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -8,19 +8,33 @@ import (
 )
 
 type TargetRepoConfig struct {
-	TargetRepo              string   `yaml:"target_repo_name"`
-	TargetRepoURL           string   `yaml:"target_repo_url"`
-	TargetRepoBranch        string   `yaml:"target_repo_branch"`
+	TargetRepo       string `yaml:"target_repo_name"`
+	TargetRepoURL    string `yaml:"target_repo_url"`
+	TargetRepoBranch string `yaml:"target_repo_branch"`
+	// a tag or commit to check out instead of the tip of the branch
+	TargetRepoCommit string `yaml:"target_repo_commit"`
+	// a local working tree to fuzz instead of cloning target_repo_url
+	TargetLocalPath         string   `yaml:"target_local_path"`
 	TargetRepoImportPrefix  string   `yaml:"target_repo_import_prefix"`
 	TargetModuleDeclaration string   `yaml:"target_mod_self_declaration"`
 	TargetGoVersion         string   `yaml:"go_version"`
+	Runner                  string   `yaml:"runner"`
 	HarnessGenDeps          []string `yaml:"harness_gen_deps"`
 	IgnorePackages          []string `yaml:"ignore_packages"`
 	IgnoreFunctions         []string `yaml:"ignore_functions"`
-	IgnoreTypes             []string `yaml:"ignore_types"`
-	//TODO:
-	//substitute_packages:
-	TestTimeSeconds int `yaml:"seconds_per_target_function"`
+	// when set only the packages and functions matching them get harnesses
+	IncludePackages  []string `yaml:"include_packages"`
+	IncludeFunctions []string `yaml:"include_functions"`
+	IgnoreTypes      []string `yaml:"ignore_types"`
+	// import paths generated harnesses import in place of the real ones
+	SubstitutePackages map[string]string `yaml:"substitute_packages"`
+	TestTimeSeconds    int               `yaml:"seconds_per_target_function"`
+	// total fuzzing time shared between the harnesses, 0 gives each one
+	// seconds_per_target_function
+	FuzzBudgetSeconds int `yaml:"fuzz_budget_seconds"`
+	// fetch the target's modules once at init and serve them to every later
+	// stage from a local module proxy, so fuzzing needs no network
+	OfflineModules bool `yaml:"offline_modules"`
 }
 
 // parse YAML file with target configuration
@@ -116,9 +116,16 @@ func (f TargetFunction) IsConstructor() bool {
 }
 
 func (f TargetFunction) IsIncludable(target_config TargetRepoConfig) bool {
+	reason, _ := f.ExcludeReason(target_config)
+	return reason == ""
+}
+
+// ExcludeReason returns the skip report reason the function is excluded
+// from harness generation for and its detail, or "" if it is included
+func (f TargetFunction) ExcludeReason(target_config TargetRepoConfig) (string, string) {
 	//f.Print()
 	if IsInterfaceReceiver(f.TypesFunc) {
-		return false
+		return ReasonInterfaceMethod, ""
 	}
 
 	// prob should add this back in at some point
@@ -138,20 +145,37 @@ func (f TargetFunction) IsIncludable(target_config TargetRepoConfig) bool {
 	//	return false
 	//}
 
-	// check that the function name is not in the ignore list
-	for _, fn := range target_config.IgnoreFunctions {
-		if fn == fmt.Sprintf("%s.%s", f.PackageName, f.Name) {
-			return false
-		}
+	// check that the function name is not in the ignore list and, if there
+	// is one, in the include list
+	names := f.QualifiedNames()
+	if entry := matchAny(target_config.IgnoreFunctions, true, names...); entry != "" {
+		return ReasonIgnoredFunction, entry
+	}
+	if len(target_config.IncludeFunctions) > 0 && matchAny(target_config.IncludeFunctions, true, names...) == "" {
+		return ReasonNotIncludedFunction, ""
 	}
 
-	for _, arg := range f.Args {
-		if !arg.IsIncludable() {
-			return false
-		}
+	// drop methods of and functions taking a type in the ignore list
+	if n := usesIgnoredType(f.TypesFunc, target_config.IgnoreTypes); n != nil {
+		return ReasonIgnoredType, n.String()
+	}
+	if n := usesSubstitutedPackage(f.TypesFunc, target_config.SubstitutePackages); n != nil {
+		return ReasonSubstitutedType, n.String()
 	}
 
-	return true
+	// parameters the fuzzer cannot provide are reported with their type
+	// once the harness is generated
+	return "", ""
+}
+
+// QualifiedNames returns the names function patterns are matched against:
+// Function and pkg.Function, or Type.Method and pkg.Type.Method for methods
+func (f TargetFunction) QualifiedNames() []string {
+	name := f.Name
+	if n := receiver(f.TypesFunc); n != nil {
+		name = n.Obj().Name() + "." + f.Name
+	}
+	return []string{name, f.PackageName + "." + name}
 }
 
 // since functions can be defined more than once with the same name and
@@ -24,7 +24,7 @@ func NewTargetPackage(name string, real_path string, target_config TargetRepoCon
 	// "./fuzzing_directory/prysm/go/src/github.com/prysmaticlabs/prysm/validator/db/db"
 	// into:
 	// "github.com/prysmaticlabs/prysm/validator/db"
-	fuzz_dir_path_prefix := fmt.Sprintf("./fuzzing_directory/%s/go/src/", target_config)
+	fuzz_dir_path_prefix := fmt.Sprintf("./fuzzing_directory/%s/go/src/", target_config.TargetRepo)
 	trimmed_path := strings.TrimPrefix(real_path, fuzz_dir_path_prefix)
 	s := strings.Split(trimmed_path, "/")
 	trimmed_path = strings.TrimSuffix(trimmed_path, s[len(s)-1])
@@ -51,20 +51,22 @@ func (pkg TargetPackage) GenerateHarnessPath() string {
 		hex.EncodeToString(unique_id[:4]))
 }
 
-// checks target config to see if the package should be excluded
-func (pkg TargetPackage) in_ignore_packages(target_config TargetRepoConfig) bool {
-	for _, bad_package := range target_config.IgnorePackages {
-		if strings.Contains(pkg.ImportPath, bad_package) {
-			//fmt.Println(bad_package, " ", pkg.ImportPath)
-			return true
-		}
+// ExcludeReason returns the skip report reason the config excludes the
+// package for and the entry responsible, or "" if the package is included.
+// Entries are matched against the package's import path.
+func (pkg TargetPackage) ExcludeReason(target_config TargetRepoConfig) (string, string) {
+	if entry := matchAny(target_config.IgnorePackages, false, pkg.RealPath); entry != "" {
+		return ReasonIgnoredPackage, entry
 	}
-	return false
+	if len(target_config.IncludePackages) > 0 && matchAny(target_config.IncludePackages, false, pkg.RealPath) == "" {
+		return ReasonNotIncludedPackage, ""
+	}
+	return "", ""
 }
 
 // checks various things to see if we should exclude the package
 func (pkg TargetPackage) IsIncludable(target_config TargetRepoConfig) bool {
-	if pkg.in_ignore_packages(target_config) {
+	if reason, _ := pkg.ExcludeReason(target_config); reason != "" {
 		return false
 	}
 	//TODO: find a away around this
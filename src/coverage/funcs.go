@@ -0,0 +1,149 @@
+package coverage
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+)
+
+// Function summarizes the coverage of one function, its Name is the function
+// name prefixed by its receiver's type name for methods
+type Function struct {
+	Summary
+	Package  string
+	Receiver string
+	Func     string
+	// File is the host path of the source file
+	File string
+	Line int
+	// Blocks is the number of basic blocks of the function, Reached is set
+	// when its first block ran and PastFirst when any later block did
+	Blocks    int
+	Reached   bool
+	PastFirst bool
+}
+
+// Harness returns the name parse-package gives the function's harness
+func (f Function) Harness() string {
+	if f.Receiver == "" {
+		return fmt.Sprintf("Fuzz_Nosy_%s__", f.Func)
+	}
+	return fmt.Sprintf("Fuzz_Nosy_%s_%s__", f.Receiver, f.Func)
+}
+
+// Stuck reports whether the fuzzer never got past the function's first
+// statement, either because it was never called or because every input
+// returned or panicked within its first block
+func (f Function) Stuck() bool {
+	return !f.Reached || (f.Blocks > 1 && !f.PastFirst)
+}
+
+// Functions summarizes the profile per function, resolve maps the file of a
+// profile block to the host path of its source, files it cannot resolve are
+// skipped
+func (p *Profile) Functions(resolve func(file string) (string, bool)) ([]Function, error) {
+	by_file := map[string][]*Block{}
+	for _, b := range p.Blocks() {
+		by_file[b.File] = append(by_file[b.File], b)
+	}
+	var functions []Function
+	for file, blocks := range by_file {
+		host_path, ok := resolve(file)
+		if !ok {
+			continue
+		}
+		fset := token.NewFileSet()
+		parsed, err := parser.ParseFile(fset, host_path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range parsed.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			start := fset.Position(fn.Body.Lbrace)
+			end := fset.Position(fn.Body.Rbrace)
+			f := Function{
+				Package:  path.Dir(file),
+				Receiver: receiver_name(fn),
+				Func:     fn.Name.Name,
+				File:     host_path,
+				Line:     fset.Position(fn.Pos()).Line,
+			}
+			f.Name = f.Func
+			if f.Receiver != "" {
+				f.Name = f.Receiver + "." + f.Func
+			}
+			// blocks are ordered, so the first one inside the body is the
+			// function's entry block
+			for _, b := range blocks {
+				if !within(b, start, end) {
+					continue
+				}
+				f.Statements += b.NumStmt
+				if b.Count > 0 {
+					f.Covered += b.NumStmt
+					if f.Blocks == 0 {
+						f.Reached = true
+					} else {
+						f.PastFirst = true
+					}
+				}
+				f.Blocks++
+			}
+			if f.Blocks > 0 {
+				functions = append(functions, f)
+			}
+		}
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].File != functions[j].File {
+			return functions[i].File < functions[j].File
+		}
+		return functions[i].Line < functions[j].Line
+	})
+	return functions, nil
+}
+
+// within reports whether a block starts between two positions
+func within(b *Block, start, end token.Position) bool {
+	if b.StartLine < start.Line || b.StartLine > end.Line {
+		return false
+	}
+	if b.StartLine == start.Line && b.StartCol < start.Column {
+		return false
+	}
+	if b.StartLine == end.Line && b.StartCol > end.Column {
+		return false
+	}
+	return true
+}
+
+// receiver_name returns the name of a method's receiver type without its
+// pointer or type parameters, or "" for plain functions
+func receiver_name(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	expr := fn.Recv.List[0].Type
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
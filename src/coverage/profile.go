@@ -0,0 +1,225 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Block is one basic block of a coverage profile
+type Block struct {
+	// File is the import path of the package followed by the file name
+	File      string
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
+}
+
+// key identifies a block across profiles
+func (b *Block) key() string {
+	return fmt.Sprintf("%s:%d.%d,%d.%d", b.File, b.StartLine, b.StartCol, b.EndLine, b.EndCol)
+}
+
+// before reports whether b starts before o within the same file
+func (b *Block) before(o *Block) bool {
+	if b.StartLine != o.StartLine {
+		return b.StartLine < o.StartLine
+	}
+	return b.StartCol < o.StartCol
+}
+
+// Profile is a coverage profile as written by go test -coverprofile
+type Profile struct {
+	Mode   string
+	blocks map[string]*Block
+}
+
+// NewProfile returns an empty profile
+func NewProfile(mode string) *Profile {
+	return &Profile{Mode: mode, blocks: map[string]*Block{}}
+}
+
+// Parse reads a coverage profile
+func Parse(r io.Reader) (*Profile, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var p *Profile
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if p == nil {
+			if !strings.HasPrefix(line, "mode: ") {
+				return nil, fmt.Errorf("not a coverage profile, missing mode line")
+			}
+			p = NewProfile(strings.TrimPrefix(line, "mode: "))
+			continue
+		}
+		b, err := parse_block(line)
+		if err != nil {
+			return nil, err
+		}
+		p.add(b)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("empty coverage profile")
+	}
+	return p, nil
+}
+
+// ParseFile reads the coverage profile at profile_path
+func ParseFile(profile_path string) (*Profile, error) {
+	f, err := os.Open(profile_path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", profile_path, err)
+	}
+	return p, nil
+}
+
+// parse_block parses a file:startLine.startCol,endLine.endCol numStmt count
+// profile line
+func parse_block(line string) (*Block, error) {
+	colon := strings.LastIndex(line, ":")
+	fields := strings.Fields(line[colon+1:])
+	if colon < 0 || len(fields) != 3 {
+		return nil, fmt.Errorf("malformed coverage line %q", line)
+	}
+	b := &Block{File: line[:colon]}
+	var err error
+	parse := func(s string) int {
+		n, e := strconv.Atoi(s)
+		if e != nil && err == nil {
+			err = fmt.Errorf("malformed coverage line %q: %w", line, e)
+		}
+		return n
+	}
+	start, end, _ := strings.Cut(fields[0], ",")
+	start_line, start_col, _ := strings.Cut(start, ".")
+	end_line, end_col, _ := strings.Cut(end, ".")
+	b.StartLine, b.StartCol = parse(start_line), parse(start_col)
+	b.EndLine, b.EndCol = parse(end_line), parse(end_col)
+	b.NumStmt, b.Count = parse(fields[1]), parse(fields[2])
+	return b, err
+}
+
+// add merges a block into the profile
+func (p *Profile) add(b *Block) {
+	known, ok := p.blocks[b.key()]
+	switch {
+	case !ok:
+		p.blocks[b.key()] = b
+	case p.Mode == "set":
+		if b.Count > known.Count {
+			known.Count = b.Count
+		}
+	default:
+		known.Count += b.Count
+	}
+}
+
+// Merge adds the counts of another profile of the same mode
+func (p *Profile) Merge(o *Profile) error {
+	if o.Mode != p.Mode {
+		return fmt.Errorf("cannot merge %s coverage into %s coverage", o.Mode, p.Mode)
+	}
+	for _, b := range o.blocks {
+		copied := *b
+		p.add(&copied)
+	}
+	return nil
+}
+
+// Blocks returns the blocks ordered by file and position
+func (p *Profile) Blocks() []*Block {
+	var blocks []*Block
+	for _, b := range p.blocks {
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].File != blocks[j].File {
+			return blocks[i].File < blocks[j].File
+		}
+		return blocks[i].before(blocks[j])
+	})
+	return blocks
+}
+
+// Write writes the profile in the go test -coverprofile format
+func (p *Profile) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "mode: %s\n", p.Mode)
+	for _, b := range p.Blocks() {
+		fmt.Fprintf(bw, "%s %d %d\n", b.key(), b.NumStmt, b.Count)
+	}
+	return bw.Flush()
+}
+
+// WriteFile writes the profile to profile_path
+func (p *Profile) WriteFile(profile_path string) error {
+	f, err := os.Create(profile_path)
+	if err != nil {
+		return err
+	}
+	if err := p.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Summary counts the statements of a package or function
+type Summary struct {
+	Name       string
+	Statements int
+	Covered    int
+}
+
+// Percent returns the share of covered statements
+func (s Summary) Percent() float64 {
+	if s.Statements == 0 {
+		return 0
+	}
+	return 100 * float64(s.Covered) / float64(s.Statements)
+}
+
+// Packages summarizes the profile per package
+func (p *Profile) Packages() []Summary {
+	by_package := map[string]*Summary{}
+	for _, b := range p.blocks {
+		pkg := path.Dir(b.File)
+		s, ok := by_package[pkg]
+		if !ok {
+			s = &Summary{Name: pkg}
+			by_package[pkg] = s
+		}
+		s.Statements += b.NumStmt
+		if b.Count > 0 {
+			s.Covered += b.NumStmt
+		}
+	}
+	var summaries []Summary
+	for _, s := range by_package {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
@@ -0,0 +1,154 @@
+package coverage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file   string
+		mode   string
+		blocks int
+		first  Block
+	}{
+		{
+			file:   "header.out",
+			mode:   "count",
+			blocks: 10,
+			first:  Block{File: "example.com/fake/parse/parse.go", StartLine: 5, StartCol: 2, EndLine: 5, EndCol: 46, NumStmt: 1, Count: 2},
+		},
+		{
+			file:   "other.out",
+			mode:   "count",
+			blocks: 3,
+			first:  Block{File: "example.com/fake/other/other.go", StartLine: 5, StartCol: 2, EndLine: 5, EndCol: 31, NumStmt: 1, Count: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			p, err := ParseFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Mode != tt.mode {
+				t.Errorf("Mode = %q, want %q", p.Mode, tt.mode)
+			}
+			blocks := p.Blocks()
+			if len(blocks) != tt.blocks {
+				t.Fatalf("got %d blocks, want %d", len(blocks), tt.blocks)
+			}
+			if *blocks[0] != tt.first {
+				t.Errorf("first block = %+v, want %+v", *blocks[0], tt.first)
+			}
+
+			// written back the profile is what go test wrote
+			want, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := p.Write(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != string(want) {
+				t.Errorf("Write() =\n%s\nwant\n%s", got.String(), want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+	}{
+		{"empty", ""},
+		{"no mode", "example.com/fake/parse/parse.go:5.2,5.46 1 2\n"},
+		{"missing count", "mode: set\nexample.com/fake/parse/parse.go:5.2,5.46 1\n"},
+		{"no position", "mode: set\nparse.go 1 2\n"},
+		{"bad number", "mode: set\nexample.com/fake/parse/parse.go:5.x,5.46 1 2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.profile)); err == nil {
+				t.Errorf("Parse(%q) succeeded, want an error", tt.profile)
+			}
+		})
+	}
+}
+
+// merging the profiles of every harness of the fake target gives the
+// profile nosy coverage merged from them
+func TestMergeHarnesses(t *testing.T) {
+	var merged *Profile
+	for _, file := range []string{"header.out", "other.out", "div.out", "sum.out"} {
+		p, err := ParseFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if merged == nil {
+			merged = NewProfile(p.Mode)
+		}
+		if err := merged.Merge(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "merged.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if err := merged.Write(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != string(want) {
+		t.Errorf("merged profile =\n%s\nwant\n%s", got.String(), want)
+	}
+
+	packages := []Summary{
+		{Name: "example.com/fake/other", Statements: 3, Covered: 2},
+		{Name: "example.com/fake/parse", Statements: 11, Covered: 6},
+	}
+	if got := merged.Packages(); !reflect.DeepEqual(got, packages) {
+		t.Errorf("Packages() = %+v, want %+v", got, packages)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	const block = "example.com/fake/parse/parse.go:5.2,5.46 1 "
+	tests := []struct {
+		name  string
+		a, b  string
+		count int
+		err   bool
+	}{
+		{"count adds", "mode: count\n" + block + "2\n", "mode: count\n" + block + "3\n", 5, false},
+		{"atomic adds", "mode: atomic\n" + block + "2\n", "mode: atomic\n" + block + "3\n", 5, false},
+		{"set keeps one", "mode: set\n" + block + "1\n", "mode: set\n" + block + "1\n", 1, false},
+		{"set covered by either", "mode: set\n" + block + "0\n", "mode: set\n" + block + "1\n", 1, false},
+		{"modes differ", "mode: set\n" + block + "1\n", "mode: count\n" + block + "1\n", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := Parse(strings.NewReader(tt.a))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Parse(strings.NewReader(tt.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := a.Merge(b); (err != nil) != tt.err {
+				t.Fatalf("Merge() error = %v, want error %t", err, tt.err)
+			}
+			if got := a.Blocks()[0].Count; got != tt.count {
+				t.Errorf("count = %d, want %d", got, tt.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package minimize
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// header is the first line of every go test fuzz corpus file
+const header = "go test fuzz v1"
+
+// Value is one argument of a fuzz input
+type Value struct {
+	// Type is the Go type the value is written as, e.g. []byte or int64
+	Type string
+	// Data holds the contents of []byte and string values
+	Data []byte
+	// Lit is the literal of every other value, e.g. 42, 'a' or true
+	Lit string
+}
+
+// IsBytes reports whether the value is a []byte or string
+func (v Value) IsBytes() bool {
+	return v.Type == "[]byte" || v.Type == "string"
+}
+
+// Input is a fuzz input as saved by go test under testdata/fuzz
+type Input []Value
+
+// Parse reads a go test fuzz corpus file
+func Parse(data []byte) (Input, error) {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != header {
+		return nil, fmt.Errorf("not a go test fuzz corpus file, missing %q header", header)
+	}
+	var input Input
+	for i, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		v, err := parse_value(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+		input = append(input, v)
+	}
+	return input, nil
+}
+
+// parse_value parses a single T(literal) line of a corpus file
+func parse_value(line string) (Value, error) {
+	expr, err := parser.ParseExpr(line)
+	if err != nil {
+		return Value{}, err
+	}
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return Value{}, fmt.Errorf("malformed value %q", line)
+	}
+	typ := line[:call.Lparen-1]
+	lit := line[call.Args[0].Pos()-1 : call.Args[0].End()-1]
+
+	v := Value{Type: typ}
+	switch typ {
+	case "[]byte", "string":
+		basic, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || basic.Kind != token.STRING {
+			return Value{}, fmt.Errorf("%s value must be a string literal: %q", typ, line)
+		}
+		s, err := strconv.Unquote(basic.Value)
+		if err != nil {
+			return Value{}, err
+		}
+		v.Data = []byte(s)
+	default:
+		v.Lit = lit
+	}
+	return v, nil
+}
+
+// Marshal writes the input in the go test fuzz corpus format
+func (input Input) Marshal() []byte {
+	var b bytes.Buffer
+	b.WriteString(header + "\n")
+	for _, v := range input {
+		if v.IsBytes() {
+			fmt.Fprintf(&b, "%s(%s)\n", v.Type, strconv.Quote(string(v.Data)))
+		} else {
+			fmt.Fprintf(&b, "%s(%s)\n", v.Type, v.Lit)
+		}
+	}
+	return b.Bytes()
+}
+
+// clone returns a deep copy of the input
+func (input Input) clone() Input {
+	out := make(Input, len(input))
+	for i, v := range input {
+		out[i] = v
+		if v.Data != nil {
+			out[i].Data = append([]byte{}, v.Data...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,157 @@
+package minimize
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file string
+		want Input
+	}{
+		{
+			file: "bytes",
+			want: Input{{Type: "[]byte", Data: []byte("X\x00\x000")}},
+		},
+		{
+			file: "ints",
+			want: Input{{Type: "int", Lit: "-47"}, {Type: "int", Lit: "7"}},
+		},
+		{
+			file: "kinds",
+			want: Input{
+				{Type: "[]byte", Data: []byte("0")},
+				{Type: "string", Data: []byte("000")},
+				{Type: "rune", Lit: "'¿'"},
+				{Type: "byte", Lit: "'g'"},
+				{Type: "int64", Lit: "-3"},
+				{Type: "uint16", Lit: "9"},
+				{Type: "float64", Lit: "1.5"},
+				{Type: "bool", Lit: "false"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			input, err := Parse(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(input, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", input, tt.want)
+			}
+			// go test reads back what it wrote, and so must the fuzzer
+			// read back what the minimizer writes
+			if got := input.Marshal(); !bytes.Equal(got, data) {
+				t.Errorf("Marshal() = %q, want %q", got, data)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"no header", "[]byte(\"a\")\n"},
+		{"other version", "go test fuzz v2\nint(1)\n"},
+		{"not a call", "go test fuzz v1\n42\n"},
+		{"two arguments", "go test fuzz v1\nint(1, 2)\n"},
+		{"bytes from a number", "go test fuzz v1\n[]byte(42)\n"},
+		{"unterminated", "go test fuzz v1\nstring(\"a)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if input, err := Parse([]byte(tt.data)); err == nil {
+				t.Errorf("Parse(%q) = %+v, want an error", tt.data, input)
+			}
+		})
+	}
+}
+
+// every candidate the minimizer proposes is written to a corpus file and
+// must come back unchanged
+func TestCandidatesRoundTrip(t *testing.T) {
+	for _, file := range []string{"bytes", "ints", "kinds"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			input, err := Parse(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := New(input)
+			candidates := 0
+			for !m.Done() {
+				for _, c := range m.Next(16) {
+					candidates++
+					back, err := Parse(c.Marshal())
+					if err != nil {
+						t.Fatalf("candidate %q: %v", c.Marshal(), err)
+					}
+					if !bytes.Equal(back.Marshal(), c.Marshal()) {
+						t.Errorf("candidate %q came back as %q", c.Marshal(), back.Marshal())
+					}
+					if !Less(c, input) {
+						t.Errorf("candidate %q is not smaller than %q", c.Marshal(), data)
+					}
+				}
+			}
+			if candidates == 0 {
+				t.Errorf("no candidates for %q", data)
+			}
+		})
+	}
+}
+
+// minimizing against an oracle that only needs an X in the first value ends
+// with just that byte, and the scalars simplified
+func TestMinimize(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "kinds"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input[0].Data = []byte("abcXdef")
+	crashes := func(in Input) bool {
+		return bytes.Contains(in[0].Data, []byte("X"))
+	}
+
+	m := New(input)
+	for round := 0; round < 1000 && !m.Done(); round++ {
+		var smallest Input
+		for _, c := range m.Next(8) {
+			if crashes(c) && (smallest == nil || Less(c, smallest)) {
+				smallest = c
+			}
+		}
+		if smallest != nil {
+			m.Accept(smallest)
+		}
+	}
+	best := m.Best()
+	if !bytes.Equal(best[0].Data, []byte("X")) {
+		t.Errorf("first value minimized to %q, want \"X\"", best[0].Data)
+	}
+	if best[4].Lit != "0" {
+		t.Errorf("int64 value minimized to %s, want 0", best[4].Lit)
+	}
+	if best.Size() >= input.Size() {
+		t.Errorf("minimized size %d is not below %d", best.Size(), input.Size())
+	}
+}
@@ -0,0 +1,196 @@
+package minimize
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// kinds of edits tried on an input, in the order they are tried
+const (
+	simplify_scalar = iota // set a scalar to its zero value or halve it
+	remove_chunk           // delete a range of bytes
+	zero_chunk             // overwrite a range of bytes with zeroes
+)
+
+// edit is a single change to one value of an input
+type edit struct {
+	kind   int
+	value  int
+	offset int
+	size   int
+	lit    string
+}
+
+// Minimizer shrinks a crashing input. It only proposes candidates, deciding
+// whether a candidate still crashes the same way is up to the caller:
+//
+//	m := minimize.New(input)
+//	for !m.Done() {
+//		batch := m.Next(64)
+//		... run the batch, m.Accept the smallest one that still crashes
+//	}
+type Minimizer struct {
+	best  Input
+	edits []edit
+	next  int
+}
+
+// New returns a minimizer starting from input
+func New(input Input) *Minimizer {
+	m := &Minimizer{}
+	m.Accept(input)
+	return m
+}
+
+// Best returns the smallest input accepted so far
+func (m *Minimizer) Best() Input {
+	return m.best
+}
+
+// Done reports whether every candidate derived from the best input has been
+// proposed
+func (m *Minimizer) Done() bool {
+	return m.next >= len(m.edits)
+}
+
+// Accept makes candidate the new best input, further candidates are derived
+// from it
+func (m *Minimizer) Accept(candidate Input) {
+	m.best = candidate.clone()
+	m.edits = edits(m.best)
+	m.next = 0
+}
+
+// Next returns up to n candidates that have not been proposed yet, largest
+// reductions first. Every candidate is smaller than the best input.
+func (m *Minimizer) Next(n int) []Input {
+	var batch []Input
+	for len(batch) < n && m.next < len(m.edits) {
+		candidate := apply(m.best, m.edits[m.next])
+		m.next++
+		if Less(candidate, m.best) {
+			batch = append(batch, candidate)
+		}
+	}
+	return batch
+}
+
+// edits lists every edit to try on input, most aggressive first: scalars are
+// simplified, then ever smaller chunks of bytes are removed, down to single
+// bytes, then zeroed
+func edits(input Input) []edit {
+	var out []edit
+	for i, v := range input {
+		if v.IsBytes() {
+			continue
+		}
+		for _, lit := range simpler_literals(v) {
+			out = append(out, edit{kind: simplify_scalar, value: i, lit: lit})
+		}
+	}
+	for _, kind := range []int{remove_chunk, zero_chunk} {
+		for i, v := range input {
+			if !v.IsBytes() || len(v.Data) == 0 {
+				continue
+			}
+			if kind == remove_chunk {
+				out = append(out, edit{kind: kind, value: i, size: len(v.Data)})
+			}
+			for size := len(v.Data) / 2; size >= 1; size /= 2 {
+				for offset := 0; offset < len(v.Data); offset += size {
+					out = append(out, edit{kind: kind, value: i, offset: offset, size: size})
+				}
+			}
+		}
+	}
+	return out
+}
+
+// apply returns a copy of input with e applied
+func apply(input Input, e edit) Input {
+	out := input.clone()
+	v := &out[e.value]
+	end := e.offset + e.size
+	if end > len(v.Data) {
+		end = len(v.Data)
+	}
+	switch e.kind {
+	case simplify_scalar:
+		v.Lit = e.lit
+	case remove_chunk:
+		v.Data = append(v.Data[:e.offset], v.Data[end:]...)
+	case zero_chunk:
+		for i := e.offset; i < end; i++ {
+			v.Data[i] = 0
+		}
+	}
+	return out
+}
+
+// zero_literal returns the simplest literal of a scalar type
+func zero_literal(typ string) string {
+	switch typ {
+	case "bool":
+		return "false"
+	case "byte", "rune":
+		return `'\x00'`
+	default:
+		return "0"
+	}
+}
+
+// simpler_literals returns the literals to try in place of a scalar value
+func simpler_literals(v Value) []string {
+	zero := zero_literal(v.Type)
+	if v.Lit == zero {
+		return nil
+	}
+	lits := []string{zero}
+	if n, err := strconv.ParseInt(v.Lit, 0, 64); err == nil && (n > 1 || n < -1) {
+		lits = append(lits, strconv.FormatInt(n/2, 10))
+	} else if u, err := strconv.ParseUint(v.Lit, 0, 64); err == nil && u > 1 {
+		lits = append(lits, strconv.FormatUint(u/2, 10))
+	}
+	return lits
+}
+
+// score measures how large an input is: its length, then how many of its
+// bytes and scalars are not zero, then the magnitude of its integers
+func score(input Input) [3]float64 {
+	var s [3]float64
+	for _, v := range input {
+		if v.IsBytes() {
+			s[0] += float64(len(v.Data))
+			for _, b := range v.Data {
+				if b != 0 {
+					s[1]++
+				}
+			}
+			continue
+		}
+		if v.Lit != zero_literal(v.Type) {
+			s[1]++
+		}
+		if f, err := strconv.ParseFloat(strings.TrimPrefix(v.Lit, "+"), 64); err == nil {
+			s[2] += math.Abs(f)
+		}
+	}
+	return s
+}
+
+// Less reports whether input a is smaller than input b
+func Less(a Input, b Input) bool {
+	sa, sb := score(a), score(b)
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return sa[i] < sb[i]
+		}
+	}
+	return false
+}
+
+// Size returns the number of bytes in the input's []byte and string values
+func (input Input) Size() int {
+	return int(score(input)[0])
+}
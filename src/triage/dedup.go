@@ -0,0 +1,257 @@
+package triage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultFrames is the number of stack frames that identify a bucket
+const DefaultFrames = 5
+
+// version of the bucket file format
+const BucketsVersion = 1
+
+// prefix of every harness nosy generates
+const harness_prefix = "Fuzz_Nosy_"
+
+// Frames returns the normalized functions of a panicking goroutine's stack,
+// innermost first. The panic machinery above the panicking function and the
+// generated harness wrapper with everything below it are dropped, as are
+// arguments, so the same bug found through different harnesses or inputs
+// yields the same frames.
+func Frames(stack []string) []string {
+	var frames []string
+	for _, line := range stack {
+		if line == "" || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, " ") ||
+			strings.HasPrefix(line, "goroutine ") || strings.HasPrefix(line, "created by ") {
+			continue
+		}
+		frames = append(frames, normalize_frame(line))
+	}
+
+	// skip everything up to and including the last call to panic
+	for i := len(frames) - 1; i >= 0; i-- {
+		if frames[i] == "panic" || frames[i] == "runtime.gopanic" {
+			frames = frames[i+1:]
+			break
+		}
+	}
+
+	for i, frame := range frames {
+		if strings.Contains(frame, "."+harness_prefix) {
+			return frames[:i]
+		}
+	}
+	return frames
+}
+
+// normalize_frame strips the argument list off a stack trace function line
+func normalize_frame(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			line = line[:i]
+		}
+	}
+	return line
+}
+
+var number_re = regexp.MustCompile(`0x[0-9a-fA-F]+|\d+`)
+
+// Signature returns the frames that identify the crash's bucket, at most n.
+// Crashes without a usable stack are identified by their panic message with
+// numbers (indices, lengths, addresses) removed.
+func (c Crash) Signature(n int) []string {
+	frames := Frames(c.Stack)
+	if len(frames) > n {
+		frames = frames[:n]
+	}
+	if len(frames) == 0 {
+		return []string{"panic: " + number_re.ReplaceAllString(c.Panic, "N")}
+	}
+	return frames
+}
+
+// BucketID hashes a crash signature
+func BucketID(signature []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(signature, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Bucket groups every crash with the same signature
+type Bucket struct {
+	ID        string   `json:"id"`
+	Signature []string `json:"signature"`
+	Panic     string   `json:"panic"`
+	Harnesses []string `json:"harnesses"`
+	// Representative is the input kept for the bucket
+	Representative string `json:"representative,omitempty"`
+	// Minimized is the smallest input found that still hits the bucket
+	Minimized string `json:"minimized,omitempty"`
+	// Regression is the regression test generated for the bucket
+	Regression string `json:"regression,omitempty"`
+	// Inputs are all crashing inputs seen, as harness/input-id
+	Inputs []string `json:"inputs"`
+	// Commits are the commits of the target the bucket was hit on
+	Commits   []string  `json:"commits,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Duplicates returns how many inputs beyond the representative hit the bucket
+func (b *Bucket) Duplicates() int {
+	if len(b.Inputs) == 0 {
+		return 0
+	}
+	return len(b.Inputs) - 1
+}
+
+// Buckets is the persistent set of known crash buckets of a target
+type Buckets struct {
+	Version int                `json:"version"`
+	Frames  int                `json:"frames"`
+	Buckets map[string]*Bucket `json:"buckets"`
+}
+
+// NewBuckets returns an empty set of buckets keyed on the top frames frames
+func NewBuckets(frames int) *Buckets {
+	if frames <= 0 {
+		frames = DefaultFrames
+	}
+	return &Buckets{Version: BucketsVersion, Frames: frames, Buckets: map[string]*Bucket{}}
+}
+
+// LoadBuckets reads the buckets saved at path, or returns an empty set if
+// there is no such file
+func LoadBuckets(path string, frames int) (*Buckets, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewBuckets(frames), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := &Buckets{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if b.Version != BucketsVersion {
+		return nil, fmt.Errorf("%s: unsupported bucket file version %d", path, b.Version)
+	}
+	if b.Buckets == nil {
+		b.Buckets = map[string]*Bucket{}
+	}
+	return b, nil
+}
+
+// Save writes the buckets to path
+func (b *Buckets) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Add files a crash into its bucket and returns the bucket, and whether the
+// bucket was not known before
+func (b *Buckets) Add(c *Crash) (*Bucket, bool) {
+	signature := c.Signature(b.Frames)
+	id := BucketID(signature)
+	c.Bucket = id
+
+	now := time.Now().UTC()
+	bucket, known := b.Buckets[id]
+	if !known {
+		bucket = &Bucket{ID: id, Signature: signature, Panic: c.Panic, FirstSeen: now}
+		b.Buckets[id] = bucket
+	}
+	bucket.LastSeen = now
+	if !contains(bucket.Harnesses, c.Harness) {
+		bucket.Harnesses = append(bucket.Harnesses, c.Harness)
+		sort.Strings(bucket.Harnesses)
+	}
+	input := c.Harness + "/" + c.InputID()
+	if c.InputID() != "" && !contains(bucket.Inputs, input) {
+		bucket.Inputs = append(bucket.Inputs, input)
+	}
+	if c.Commit != "" && !contains(bucket.Commits, c.Commit) {
+		bucket.Commits = append(bucket.Commits, c.Commit)
+	}
+	if bucket.Representative == "" && c.Result != "" {
+		bucket.Representative = c.Result
+	}
+	return bucket, !known
+}
+
+// Sorted returns the buckets, most duplicated first
+func (b *Buckets) Sorted() []*Bucket {
+	var buckets []*Bucket
+	for _, bucket := range b.Buckets {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		if len(buckets[i].Inputs) != len(buckets[j].Inputs) {
+			return len(buckets[i].Inputs) > len(buckets[j].Inputs)
+		}
+		return buckets[i].ID < buckets[j].ID
+	})
+	return buckets
+}
+
+// KeepRepresentative copies a bucket's representative input to
+// dir/<id>/<harness key>/<input-id>, so it survives cleaning up the results
+// directory, and points the bucket at the copy
+func (bucket *Bucket) KeepRepresentative(dir string) error {
+	if bucket.Representative == "" || strings.HasPrefix(bucket.Representative, dir+string(filepath.Separator)) {
+		return nil
+	}
+	src, err := os.Open(bucket.Representative)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	harness := filepath.Base(filepath.Dir(bucket.Representative))
+	kept := filepath.Join(dir, bucket.ID, harness, filepath.Base(bucket.Representative))
+	if err := os.MkdirAll(filepath.Dir(kept), 0o755); err != nil {
+		return err
+	}
+	dst, err := os.Create(kept)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	bucket.Representative = kept
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package triage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	tests := []struct {
+		name  string
+		stack []string
+		want  []string
+	}{
+		{
+			name: "panic in the target",
+			stack: []string{
+				"goroutine 58 [running]:",
+				"runtime/debug.Stack()",
+				"\t/usr/local/go/src/runtime/debug/stack.go:26 +0x9b",
+				"testing.tRunner.func1()",
+				"\t/usr/local/go/src/testing/testing.go:2076 +0x1b0",
+				"panic({0x83f5b0?, 0x883540?})",
+				"\t/usr/local/go/src/runtime/panic.go:859 +0x125",
+				"example.com/fake/parse.(*Point).Div(...)",
+				"\t/go/src/example.com/fake/parse/parse.go:21",
+				"example.com/fake/parse.Fuzz_Nosy_Point_Div__.func1(0x0?, 0x0?, 0x7)",
+				"\t/go/src/example.com/fake/parse/Fuzz_Nosy_test.go:32 +0x8a",
+				"testing.tRunner(0x329e3a31db08, 0x329e3a227950)",
+			},
+			want: []string{"example.com/fake/parse.(*Point).Div"},
+		},
+		{
+			name: "repanic",
+			stack: []string{
+				"goroutine 7 [running]:",
+				"panic({0x1, 0x2})",
+				"example.com/fake/parse.recoverer()",
+				"runtime.gopanic({0x1, 0x2})",
+				"example.com/fake/parse.inner(0x3)",
+				"example.com/fake/parse.outer({0x4, 0x5})",
+				"example.com/fake/parse.Fuzz_Nosy_Outer__.func1()",
+			},
+			want: []string{"example.com/fake/parse.inner", "example.com/fake/parse.outer"},
+		},
+		{
+			name: "no harness frame",
+			stack: []string{
+				"goroutine 7 [running]:",
+				"panic({0x1, 0x2})",
+				"example.com/fake/parse.inner(0x3)",
+				"created by example.com/fake/parse.start in goroutine 1",
+			},
+			want: []string{"example.com/fake/parse.inner"},
+		},
+		{
+			name: "panic in the harness",
+			stack: []string{
+				"panic({0x1, 0x2})",
+				"example.com/fake/parse.Fuzz_Nosy_Sum__.func1()",
+			},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Frames(tt.stack)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Frames() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// the signature of each captured crash is its panicking function, or the
+// panic message with its numbers blanked out when the test binary died
+// without a stack
+func TestParsedSignature(t *testing.T) {
+	tests := []struct {
+		log  string
+		want []string
+	}{
+		{"index.log", []string{"example.com/fake/other.Header"}},
+		{"divide.log", []string{"example.com/fake/parse.(*Point).Div"}},
+		{"fatal.log", []string{"panic: header of N bytes is too long"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.log, func(t *testing.T) {
+			crashes, err := ParseFile(filepath.Join("testdata", tt.log))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(crashes) != 1 {
+				t.Fatalf("got %d crashes, want 1", len(crashes))
+			}
+			if got := crashes[0].Signature(DefaultFrames); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Signature() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignature(t *testing.T) {
+	deep := []string{"panic({0x1, 0x2})"}
+	for _, f := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		deep = append(deep, "example.com/fake/parse."+f+"()")
+	}
+	tests := []struct {
+		name  string
+		crash Crash
+		n     int
+		want  []string
+	}{
+		{
+			name:  "top frames",
+			crash: Crash{Stack: deep},
+			n:     3,
+			want:  []string{"example.com/fake/parse.a", "example.com/fake/parse.b", "example.com/fake/parse.c"},
+		},
+		{
+			name:  "fewer frames than asked for",
+			crash: Crash{Stack: deep[:3]},
+			n:     DefaultFrames,
+			want:  []string{"example.com/fake/parse.a", "example.com/fake/parse.b"},
+		},
+		{
+			name:  "no stack",
+			crash: Crash{Panic: "header of 6 bytes is too long at 0x1f"},
+			n:     DefaultFrames,
+			want:  []string{"panic: header of N bytes is too long at N"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.crash.Signature(tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Signature(%d) = %q, want %q", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+// crashes of the same bug found with different inputs share a bucket, the
+// message of a crash without a stack does not tell its numbers apart
+func TestBucketID(t *testing.T) {
+	crashes := map[string][]string{}
+	for _, log := range []string{"index.log", "divide.log", "fatal.log"} {
+		found, err := ParseFile(filepath.Join("testdata", log))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range found {
+			crashes[log] = c.Signature(DefaultFrames)
+		}
+	}
+	tests := []struct {
+		name string
+		a, b []string
+		same bool
+	}{
+		{"same crash", crashes["index.log"], crashes["index.log"], true},
+		{"different crashes", crashes["index.log"], crashes["divide.log"], false},
+		{"same message", crashes["fatal.log"], Crash{Panic: "header of 9 bytes is too long"}.Signature(DefaultFrames), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := BucketID(tt.a) == BucketID(tt.b); same != tt.same {
+				t.Errorf("BucketID(%q) == BucketID(%q) is %t, want %t", tt.a, tt.b, same, tt.same)
+			}
+		})
+	}
+}
+
+// buckets saved by one run are known to the next, which only adds the inputs
+// and harnesses it has not seen
+func TestBucketsAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buckets.json")
+	runs := []struct {
+		log     string
+		harness string
+		new     bool
+		inputs  int
+	}{
+		{"index.log", "Fuzz_Nosy_Header__", true, 1},
+		{"index.log", "Fuzz_Nosy_Header__", false, 1},
+		{"index.log", "Fuzz_Nosy_Other_Header__", false, 2},
+		{"divide.log", "Fuzz_Nosy_Point_Div__", true, 1},
+	}
+	for i, run := range runs {
+		buckets, err := LoadBuckets(path, DefaultFrames)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crashes, err := ParseFile(filepath.Join("testdata", run.log))
+		if err != nil {
+			t.Fatal(err)
+		}
+		crash := crashes[0]
+		crash.Harness = run.harness
+		bucket, new := buckets.Add(&crash)
+		if new != run.new {
+			t.Errorf("run %d: new bucket is %t, want %t", i, new, run.new)
+		}
+		if len(bucket.Inputs) != run.inputs {
+			t.Errorf("run %d: bucket has inputs %q, want %d", i, bucket.Inputs, run.inputs)
+		}
+		if crash.Bucket != bucket.ID {
+			t.Errorf("run %d: crash filed in %s, bucket is %s", i, crash.Bucket, bucket.ID)
+		}
+		if err := buckets.Save(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buckets, err := LoadBuckets(path, DefaultFrames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorted := buckets.Sorted()
+	if len(sorted) != 2 {
+		t.Fatalf("got %d buckets, want 2", len(sorted))
+	}
+	harnesses := []string{"Fuzz_Nosy_Header__", "Fuzz_Nosy_Other_Header__"}
+	if !reflect.DeepEqual(sorted[0].Harnesses, harnesses) || sorted[0].Duplicates() != 1 {
+		t.Errorf("most duplicated bucket has harnesses %q and %d duplicates, want %q and 1",
+			sorted[0].Harnesses, sorted[0].Duplicates(), harnesses)
+	}
+}
@@ -0,0 +1,190 @@
+package triage
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Crash is a failure reported by a go test -fuzz run
+type Crash struct {
+	Harness string `json:"harness"`
+	Package string `json:"package"`
+	// Input is the failing input as reported by go test, relative to the
+	// package directory, e.g. testdata/fuzz/Fuzz_Nosy_Foo__/582528ddfad69eb5
+	Input string `json:"input"`
+	// Result is where nosy saved the input on the host, if known
+	Result string `json:"result,omitempty"`
+	Panic  string `json:"panic"`
+	// Stack is the goroutine trace of the panic, one line per entry
+	Stack []string `json:"stack,omitempty"`
+	// Log is the file the crash was parsed from
+	Log string `json:"log,omitempty"`
+	// Bucket is the ID of the stack hash bucket the crash was filed in
+	Bucket string `json:"bucket,omitempty"`
+	// Commit is the commit of the target the crash was found on, if the
+	// fuzz script reported it
+	Commit string `json:"commit,omitempty"`
+}
+
+// InputID returns the name go test gave the failing input
+func (c Crash) InputID() string {
+	if c.Input == "" {
+		return ""
+	}
+	return path.Base(c.Input)
+}
+
+var (
+	fail_re    = regexp.MustCompile(`^--- FAIL: (\S+)`)
+	input_re   = regexp.MustCompile(`Failing input written to (\S+)`)
+	package_re = regexp.MustCompile(`^(?:FAIL|ok)\s+(\S+)\s`)
+	// printed by the fuzz scripts nosy generates
+	commit_re = regexp.MustCompile(`^nosy: target commit (\S+)$`)
+	// the file:line prefix of messages logged by the testing package
+	location_re  = regexp.MustCompile(`^\S+\.go:\d+: `)
+	goroutine_re = regexp.MustCompile(`^goroutine \d+ \[.*\]:$`)
+	exit_re      = regexp.MustCompile(`^exit status \d+$`)
+	// added by the testing package when a panic passes through it
+	recovered_re = regexp.MustCompile(`\s*\[recovered[^\]]*\]$`)
+)
+
+// Parse extracts every crash from the output of one or more go test -fuzz
+// runs
+func Parse(r io.Reader) ([]Crash, error) {
+	var crashes []Crash
+	// crashes of the current run that have not seen their package yet
+	pending := 0
+	var current *Crash
+	in_stack := false
+	stack_indent := ""
+	commit := ""
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if m := commit_re.FindStringSubmatch(trimmed); m != nil {
+			commit = m[1]
+			continue
+		}
+		if m := fail_re.FindStringSubmatch(line); m != nil {
+			crashes = append(crashes, Crash{Harness: m[1], Commit: commit})
+			current = &crashes[len(crashes)-1]
+			pending++
+			in_stack = false
+			continue
+		}
+		if m := package_re.FindStringSubmatch(line); m != nil {
+			for i := len(crashes) - pending; i < len(crashes); i++ {
+				crashes[i].Package = m[1]
+			}
+			pending = 0
+			current = nil
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		if in_stack {
+			if trimmed == "" || exit_re.MatchString(trimmed) {
+				in_stack = false
+				continue
+			}
+			current.Stack = append(current.Stack, strings.TrimPrefix(line, stack_indent))
+			continue
+		}
+
+		switch {
+		case goroutine_re.MatchString(trimmed) && current.Stack == nil:
+			in_stack = true
+			stack_indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			current.Stack = append(current.Stack, trimmed)
+		case input_re.MatchString(line):
+			current.Input = input_re.FindStringSubmatch(line)[1]
+		case current.Panic == "":
+			current.Panic = failure_message(trimmed)
+		}
+	}
+	return crashes, scanner.Err()
+}
+
+// failure_message returns the interesting part of the first line of a
+// failure, or "" if the line does not describe the failure
+func failure_message(line string) string {
+	if line == "" || strings.HasPrefix(line, "--- FAIL:") {
+		return ""
+	}
+	line = location_re.ReplaceAllString(line, "")
+	if i := strings.Index(line, "panic: "); i >= 0 {
+		line = line[i+len("panic: "):]
+	}
+	line = recovered_re.ReplaceAllString(line, "")
+	return strings.TrimSpace(line)
+}
+
+// ParseFile parses a saved fuzzing log
+func ParseFile(log_path string) ([]Crash, error) {
+	f, err := os.Open(log_path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	crashes, err := Parse(f)
+	for i := range crashes {
+		crashes[i].Log = log_path
+	}
+	return crashes, err
+}
+
+// WriteCSV writes one row per crash
+func WriteCSV(w io.Writer, crashes []Crash) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"harness", "package", "bucket", "panic", "input", "result", "log", "commit"})
+	for _, c := range crashes {
+		cw.Write([]string{c.Harness, c.Package, c.Bucket, c.Panic, c.Input, c.Result, c.Log, c.Commit})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the crashes, including their stack traces
+func WriteJSON(w io.Writer, crashes []Crash) error {
+	if crashes == nil {
+		crashes = []Crash{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(crashes)
+}
+
+// WriteReports writes the crashes as CSV and JSON to the given files.
+// An empty path skips that format.
+func WriteReports(crashes []Crash, csv_path string, json_path string) error {
+	write := func(path string, fn func(w io.Writer, crashes []Crash) error) error {
+		if path == "" {
+			return nil
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := fn(f, crashes); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	if err := write(csv_path, WriteCSV); err != nil {
+		return err
+	}
+	return write(json_path, WriteJSON)
+}
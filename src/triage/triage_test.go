@@ -0,0 +1,120 @@
+package triage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// commit the fixtures were fuzzed at
+const fixture_commit = "68840eed5252ee6f23a80cf9e3f5767c9a383cd3"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		logs []string
+		want []Crash
+		// first line of each crash's stack
+		goroutines []string
+	}{
+		{
+			logs: []string{"index.log"},
+			want: []Crash{{
+				Harness: "Fuzz_Nosy_Header__",
+				Package: "example.com/fake/other",
+				Input:   "testdata/fuzz/Fuzz_Nosy_Header__/332203dec85e7a5c",
+				Panic:   "runtime error: index out of range [48] with length 4",
+				Commit:  fixture_commit,
+			}},
+			goroutines: []string{"goroutine 627 [running]:"},
+		},
+		{
+			logs: []string{"divide.log"},
+			want: []Crash{{
+				Harness: "Fuzz_Nosy_Point_Div__",
+				Package: "example.com/fake/parse",
+				Input:   "testdata/fuzz/Fuzz_Nosy_Point_Div__/089880bb31a8d7f7",
+				Panic:   "runtime error: integer divide by zero",
+				Commit:  fixture_commit,
+			}},
+			goroutines: []string{"goroutine 58 [running]:"},
+		},
+		{
+			logs: []string{"fatal.log"},
+			want: []Crash{{
+				Harness: "Fuzz_Nosy_Check__",
+				Package: "example.com/cap/fatal",
+				Input:   "testdata/fuzz/Fuzz_Nosy_Check__/372980b6ab94e16b",
+				Panic:   "header of 6 bytes is too long",
+			}},
+			goroutines: []string{""},
+		},
+		{
+			logs: []string{"pass.log"},
+		},
+		{
+			// the logs of several runs, each crash gets the package of its
+			// own run
+			logs: []string{"pass.log", "index.log", "fatal.log", "divide.log"},
+			want: []Crash{
+				{Harness: "Fuzz_Nosy_Header__", Package: "example.com/fake/other", Commit: fixture_commit},
+				{Harness: "Fuzz_Nosy_Check__", Package: "example.com/cap/fatal", Commit: fixture_commit},
+				{Harness: "Fuzz_Nosy_Point_Div__", Package: "example.com/fake/parse", Commit: fixture_commit},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.logs, "+"), func(t *testing.T) {
+			var output []byte
+			for _, log := range tt.logs {
+				data, err := os.ReadFile(filepath.Join("testdata", log))
+				if err != nil {
+					t.Fatal(err)
+				}
+				output = append(output, data...)
+			}
+			crashes, err := Parse(strings.NewReader(string(output)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(crashes) != len(tt.want) {
+				t.Fatalf("got %d crashes, want %d: %+v", len(crashes), len(tt.want), crashes)
+			}
+			for i, c := range crashes {
+				want := tt.want[i]
+				if c.Harness != want.Harness || c.Package != want.Package || c.Commit != want.Commit {
+					t.Errorf("crash %d is %s in %s at %q, want %s in %s at %q", i,
+						c.Harness, c.Package, c.Commit, want.Harness, want.Package, want.Commit)
+				}
+				if tt.goroutines == nil {
+					continue
+				}
+				if c.Input != want.Input || c.Panic != want.Panic {
+					t.Errorf("crash %d: input %q panic %q, want input %q panic %q", i, c.Input, c.Panic, want.Input, want.Panic)
+				}
+				first := ""
+				if len(c.Stack) > 0 {
+					first = c.Stack[0]
+				}
+				if first != tt.goroutines[i] {
+					t.Errorf("crash %d: stack starts with %q, want %q", i, first, tt.goroutines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestInputID(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"testdata/fuzz/Fuzz_Nosy_Header__/332203dec85e7a5c", "332203dec85e7a5c"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := (Crash{Input: tt.input}).InputID(); got != tt.want {
+			t.Errorf("InputID(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package types
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaults of optional fields left out of a target config
+const (
+	DefaultGoVersion       = "go"
+	DefaultTestTimeSeconds = 10
+)
+
+// ConfigIssue is a problem with, or a note about, one field of a target
+// config
+type ConfigIssue struct {
+	Field   string
+	Message string
+}
+
+func (i ConfigIssue) String() string {
+	return i.Field + ": " + i.Message
+}
+
+// ConfigError lists every invalid field of a target config
+type ConfigError struct {
+	Path   string
+	Issues []ConfigIssue
+}
+
+func (e *ConfigError) Error() string {
+	if len(e.Issues) == 1 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Issues[0])
+	}
+	msg := fmt.Sprintf("%s: %d invalid fields:", e.Path, len(e.Issues))
+	for _, issue := range e.Issues {
+		msg += "\n\t" + issue.String()
+	}
+	return msg
+}
+
+// ConfigCheck is the outcome of checking a target config
+type ConfigCheck struct {
+	// Errors are fields that make the config unusable
+	Errors []ConfigIssue
+	// Warnings are keys nosy does not know, usually typos
+	Warnings []ConfigIssue
+	// Defaults are the values filled in for fields left out
+	Defaults []ConfigIssue
+}
+
+// Err returns the errors of the check as a *ConfigError, or nil
+func (c ConfigCheck) Err(path string) error {
+	if len(c.Errors) == 0 {
+		return nil
+	}
+	return &ConfigError{Path: path, Issues: c.Errors}
+}
+
+// CheckTargetConfig parses the YAML file, fills in the defaults and checks
+// every field. Read and syntax errors are returned as the error, invalid
+// fields are returned in the check for the caller to report.
+func CheckTargetConfig(yaml_file string) (TargetRepoConfig, ConfigCheck, error) {
+	var target_config TargetRepoConfig
+	var check ConfigCheck
+	data, err := os.ReadFile(yaml_file)
+	if err != nil {
+		return target_config, check, err
+	}
+	if err := target_config.Parse(data); err != nil {
+		return target_config, check, fmt.Errorf("%s: %w", yaml_file, err)
+	}
+	check.Warnings, err = UnknownConfigKeys(data)
+	if err != nil {
+		return target_config, check, fmt.Errorf("%s: %w", yaml_file, err)
+	}
+	check.Defaults = target_config.ApplyDefaults()
+	check.Errors = target_config.Validate()
+	return target_config, check, nil
+}
+
+// ConfigKeys returns the YAML keys of a target config
+func ConfigKeys() []string {
+	var keys []string
+	t := reflect.TypeOf(TargetRepoConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]; key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// UnknownConfigKeys warns about the top level keys of data that are not
+// target config keys, suggesting the closest known key
+func UnknownConfigKeys(data []byte) ([]ConfigIssue, error) {
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	known := ConfigKeys()
+	var warnings []ConfigIssue
+	for key := range raw {
+		found := false
+		for _, k := range known {
+			if k == key {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		msg := "unknown key, it is ignored"
+		if suggestion := closestKey(key, known); suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean %s?)", suggestion)
+		}
+		warnings = append(warnings, ConfigIssue{key, msg})
+	}
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Field < warnings[j].Field })
+	return warnings, nil
+}
+
+// closestKey returns the known key within a few edits of key, or ""
+func closestKey(key string, known []string) string {
+	best, best_distance := "", 4
+	for _, k := range known {
+		if d := editDistance(key, k); d < best_distance {
+			best, best_distance = k, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = cur[j-1] + 1
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if prev[j-1]+cost < cur[j] {
+				cur[j] = prev[j-1] + cost
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// ApplyDefaults fills in the optional fields left out of the config and
+// returns a note for each
+func (c *TargetRepoConfig) ApplyDefaults() []ConfigIssue {
+	var defaults []ConfigIssue
+	if c.TargetGoVersion == "" {
+		c.TargetGoVersion = DefaultGoVersion
+		defaults = append(defaults, ConfigIssue{"go_version", fmt.Sprintf("not set, using %q", c.TargetGoVersion)})
+	}
+	if c.TargetRepoImportPrefix == "" {
+		defaults = append(defaults, ConfigIssue{"target_repo_import_prefix", "not set, using the module path in the target's go.mod"})
+	}
+	if c.TargetModuleDeclaration == "" {
+		defaults = append(defaults, ConfigIssue{"target_mod_self_declaration", "not set, using the module path in the target's go.mod"})
+	}
+	if c.TestTimeSeconds == 0 {
+		c.TestTimeSeconds = DefaultTestTimeSeconds
+		defaults = append(defaults, ConfigIssue{"seconds_per_target_function", fmt.Sprintf("not set, using %d", c.TestTimeSeconds)})
+	}
+	if c.TargetRepoBranch == "" && c.TargetLocalPath == "" {
+		defaults = append(defaults, ConfigIssue{"target_repo_branch", "not set, using the repository's default branch"})
+	}
+	return defaults
+}
+
+// Validate returns a problem for every field that cannot be used
+func (c *TargetRepoConfig) Validate() []ConfigIssue {
+	var issues []ConfigIssue
+	add := func(field string, format string, args ...interface{}) {
+		issues = append(issues, ConfigIssue{field, fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case c.TargetRepo == "":
+		add("target_repo_name", "required, it names the target's directory under fuzzing_directory")
+	case c.TargetRepo == "." || c.TargetRepo == ".." || strings.ContainsAny(c.TargetRepo, "/\\ \t"):
+		add("target_repo_name", "%q must be a single directory name without spaces", c.TargetRepo)
+	}
+	if c.TargetLocalPath != "" {
+		if info, err := os.Stat(c.TargetLocalPath); err != nil {
+			add("target_local_path", "%v", err)
+		} else if !info.IsDir() {
+			add("target_local_path", "%q is not a directory", c.TargetLocalPath)
+		}
+	} else if c.TargetRepoURL == "" {
+		add("target_repo_url", "required, the git URL the target is cloned from, or set target_local_path")
+	}
+	if strings.ContainsAny(c.TargetRepoBranch, " \t") {
+		add("target_repo_branch", "%q is not a branch name", c.TargetRepoBranch)
+	}
+	if strings.ContainsAny(c.TargetRepoCommit, " \t") || strings.HasPrefix(c.TargetRepoCommit, "-") {
+		add("target_repo_commit", "%q is not a commit or tag", c.TargetRepoCommit)
+	}
+	if strings.HasPrefix(c.TargetRepoImportPrefix, "http") || strings.HasSuffix(c.TargetRepoImportPrefix, "/") {
+		add("target_repo_import_prefix", "%q must be an import path without scheme or trailing slash, e.g. github.com/owner/repo", c.TargetRepoImportPrefix)
+	}
+	if strings.ContainsAny(c.TargetModuleDeclaration, " \t") {
+		add("target_mod_self_declaration", "%q is not a module path", c.TargetModuleDeclaration)
+	}
+	if strings.ContainsAny(c.TargetGoVersion, " \t") {
+		add("go_version", "%q must be the go command to use, e.g. go or go1.21.5", c.TargetGoVersion)
+	}
+	if c.TestTimeSeconds < 0 {
+		add("seconds_per_target_function", "must be positive, got %d", c.TestTimeSeconds)
+	}
+	if c.FuzzBudgetSeconds < 0 {
+		add("fuzz_budget_seconds", "must be positive or 0 for no budget, got %d", c.FuzzBudgetSeconds)
+	}
+	for i, dep := range c.HarnessGenDeps {
+		if strings.TrimSpace(dep) == "" {
+			add("harness_gen_deps", "entry %d is empty", i+1)
+		}
+	}
+
+	patterns := []struct {
+		key     string
+		entries []string
+	}{
+		{"ignore_packages", c.IgnorePackages},
+		{"include_packages", c.IncludePackages},
+		{"ignore_functions", c.IgnoreFunctions},
+		{"include_functions", c.IncludeFunctions},
+		{"ignore_types", c.IgnoreTypes},
+	}
+	for _, list := range patterns {
+		for i, entry := range list.entries {
+			if strings.TrimSpace(entry) == "" {
+				add(list.key, "entry %d is empty", i+1)
+				continue
+			}
+			// "re:" entries are regular expressions, see parse-package
+			if expr := strings.TrimPrefix(entry, "re:"); expr != entry && list.key != "ignore_types" {
+				if _, err := regexp.Compile(expr); err != nil {
+					add(list.key, "%q: %v", entry, err)
+				}
+			}
+		}
+	}
+	for from, to := range c.SubstitutePackages {
+		if from == "" || to == "" {
+			add("substitute_packages", "%q: %q needs an import path on both sides", from, to)
+		}
+	}
+	return issues
+}
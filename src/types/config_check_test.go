@@ -0,0 +1,147 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	local := t.TempDir()
+	file := filepath.Join(local, "go.mod")
+	if err := os.WriteFile(file, []byte("module example.com/fake\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	valid := func(change func(c *TargetRepoConfig)) TargetRepoConfig {
+		c := TargetRepoConfig{TargetRepo: "fake", TargetRepoURL: "https://example.com/fake.git"}
+		if change != nil {
+			change(&c)
+		}
+		return c
+	}
+	tests := []struct {
+		name   string
+		config TargetRepoConfig
+		fields []string
+	}{
+		{"valid", valid(nil), nil},
+		{"local path instead of url", valid(func(c *TargetRepoConfig) { c.TargetRepoURL, c.TargetLocalPath = "", local }), nil},
+		{"nothing set", TargetRepoConfig{}, []string{"target_repo_name", "target_repo_url"}},
+		{"name with a slash", valid(func(c *TargetRepoConfig) { c.TargetRepo = "owner/fake" }), []string{"target_repo_name"}},
+		{"name is a parent", valid(func(c *TargetRepoConfig) { c.TargetRepo = ".." }), []string{"target_repo_name"}},
+		{"local path missing", valid(func(c *TargetRepoConfig) { c.TargetLocalPath = filepath.Join(local, "missing") }), []string{"target_local_path"}},
+		{"local path is a file", valid(func(c *TargetRepoConfig) { c.TargetLocalPath = file }), []string{"target_local_path"}},
+		{"branch with a space", valid(func(c *TargetRepoConfig) { c.TargetRepoBranch = "my branch" }), []string{"target_repo_branch"}},
+		{"commit is a flag", valid(func(c *TargetRepoConfig) { c.TargetRepoCommit = "--all" }), []string{"target_repo_commit"}},
+		{"import prefix is a url", valid(func(c *TargetRepoConfig) { c.TargetRepoImportPrefix = "https://example.com/fake" }), []string{"target_repo_import_prefix"}},
+		{"import prefix trailing slash", valid(func(c *TargetRepoConfig) { c.TargetRepoImportPrefix = "example.com/fake/" }), []string{"target_repo_import_prefix"}},
+		{"module with a space", valid(func(c *TargetRepoConfig) { c.TargetModuleDeclaration = "example.com/ fake" }), []string{"target_mod_self_declaration"}},
+		{"go version with a space", valid(func(c *TargetRepoConfig) { c.TargetGoVersion = "go 1.21" }), []string{"go_version"}},
+		{"negative seconds", valid(func(c *TargetRepoConfig) { c.TestTimeSeconds = -1 }), []string{"seconds_per_target_function"}},
+		{"negative budget", valid(func(c *TargetRepoConfig) { c.FuzzBudgetSeconds = -1 }), []string{"fuzz_budget_seconds"}},
+		{"empty dependency", valid(func(c *TargetRepoConfig) { c.HarnessGenDeps = []string{"example.com/dep", " "} }), []string{"harness_gen_deps"}},
+		{"empty pattern", valid(func(c *TargetRepoConfig) { c.IgnorePackages = []string{""} }), []string{"ignore_packages"}},
+		{"bad regexp", valid(func(c *TargetRepoConfig) { c.IncludeFunctions = []string{"re:("} }), []string{"include_functions"}},
+		{"plain pattern is not compiled", valid(func(c *TargetRepoConfig) { c.IgnoreFunctions = []string{"(("} }), nil},
+		{"types take no regexp", valid(func(c *TargetRepoConfig) { c.IgnoreTypes = []string{"re:("} }), nil},
+		{"substitute without target", valid(func(c *TargetRepoConfig) { c.SubstitutePackages = map[string]string{"example.com/dep": ""} }), []string{"substitute_packages"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, issue := range tt.config.Validate() {
+				fields = append(fields, issue.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("Validate() reports %q, want %q", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	c := TargetRepoConfig{TargetRepoImportPrefix: "example.com/fake", TestTimeSeconds: 30}
+	var fields []string
+	for _, issue := range c.ApplyDefaults() {
+		fields = append(fields, issue.Field)
+	}
+	want := []string{"go_version", "target_mod_self_declaration", "target_repo_branch"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("ApplyDefaults() notes %q, want %q", fields, want)
+	}
+	if c.TargetGoVersion != DefaultGoVersion || c.TestTimeSeconds != 30 {
+		t.Errorf("go_version %q seconds %d, want %q and 30", c.TargetGoVersion, c.TestTimeSeconds, DefaultGoVersion)
+	}
+}
+
+func TestCheckTargetConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		err      bool
+		errors   []string
+		warnings []string
+	}{
+		{
+			name: "valid",
+			yaml: "target_repo_name: fake\ntarget_repo_url: https://example.com/fake.git\n",
+		},
+		{
+			name:     "typo",
+			yaml:     "target_repo_name: fake\ntarget_repo_url: https://example.com/fake.git\nignore_package:\n  - mocks\n",
+			warnings: []string{"ignore_package: unknown key, it is ignored (did you mean ignore_packages?)"},
+		},
+		{
+			name:     "unknown key",
+			yaml:     "target_repo_name: fake\ntarget_repo_url: https://example.com/fake.git\ncolour: blue\n",
+			warnings: []string{"colour: unknown key, it is ignored"},
+		},
+		{
+			name:   "invalid field",
+			yaml:   "target_repo_name: fake\ntarget_repo_url: https://example.com/fake.git\nseconds_per_target_function: -5\n",
+			errors: []string{"seconds_per_target_function: must be positive, got -5"},
+		},
+		{
+			name: "not yaml",
+			yaml: "target_repo_name: [fake\n",
+			err:  true,
+		},
+		{
+			name: "wrong type",
+			yaml: "seconds_per_target_function: ten\n",
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, check, err := CheckTargetConfig(path)
+			if (err != nil) != tt.err {
+				t.Fatalf("CheckTargetConfig() error = %v, want error %t", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			var errors, warnings []string
+			for _, issue := range check.Errors {
+				errors = append(errors, issue.String())
+			}
+			for _, issue := range check.Warnings {
+				warnings = append(warnings, issue.String())
+			}
+			if !reflect.DeepEqual(errors, tt.errors) {
+				t.Errorf("errors %q, want %q", errors, tt.errors)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("warnings %q, want %q", warnings, tt.warnings)
+			}
+			if (check.Err(path) != nil) != (len(tt.errors) > 0) {
+				t.Errorf("Err() = %v with %d errors", check.Err(path), len(tt.errors))
+			}
+		})
+	}
+}
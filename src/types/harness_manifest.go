@@ -0,0 +1,114 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// version of the harness manifest format, parse-package writes the same
+// format from its own copy of these types
+const HarnessManifestVersion = 1
+
+// name of the harness manifest in the target's repo root
+const HarnessManifestFile = "nosy_harnesses.json"
+
+// how a harness gets its arguments from the fuzzer
+const (
+	// the wrapper takes the arguments as native go test fuzz parameters
+	NativeSupport = "native"
+	// the wrapper decodes the arguments from a []byte with fill
+	FillSupport = "fill"
+)
+
+// Harness is one generated fuzz harness
+type Harness struct {
+	// Name is the name of the Fuzz_Nosy_ wrapper
+	Name string `json:"name"`
+	// Package is the import path of the package under test
+	Package   string `json:"package"`
+	Directory string `json:"directory"`
+	// File is the generated test file holding the wrapper
+	File     string `json:"file"`
+	Function string `json:"function"`
+	// Receiver is the receiver type of a wrapped method
+	Receiver  string `json:"receiver,omitempty"`
+	Signature string `json:"signature"`
+	// Constructor is the function the wrapper creates its receiver with
+	Constructor string `json:"constructor,omitempty"`
+	Support     string `json:"support"`
+}
+
+// Key identifies the harness within the target. Wrapper names repeat across
+// packages with functions of the same name, so the key adds a short hash of
+// the package. It names the harness's script, log and corpus directories.
+func (h Harness) Key() string {
+	id := h.Package
+	if id == "" {
+		// harnesses listed in fuzzable.txt have no package
+		id = h.Directory
+	}
+	sum := sha256.Sum256([]byte(id))
+	return h.Name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// HarnessManifest lists the harnesses of one generation run
+type HarnessManifest struct {
+	Version     int       `json:"version"`
+	Target      string    `json:"target"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Harnesses   []Harness `json:"harnesses"`
+}
+
+// LoadHarnessManifest reads the manifest at path
+func LoadHarnessManifest(path string) (*HarnessManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &HarnessManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if m.Version != HarnessManifestVersion {
+		return nil, fmt.Errorf("%s: unsupported harness manifest version %d", path, m.Version)
+	}
+	keys := map[string]bool{}
+	for i, h := range m.Harnesses {
+		if h.Name == "" || h.Directory == "" {
+			return nil, fmt.Errorf("%s: harness %d has no name or directory", path, i)
+		}
+		if keys[h.Key()] {
+			return nil, fmt.Errorf("%s: harness %s of %s is listed twice", path, h.Name, h.Package)
+		}
+		keys[h.Key()] = true
+	}
+	return m, nil
+}
+
+// Save writes the manifest to path, atomically so tooling never reads a
+// truncated file
+func (m *HarnessManifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".nosy_harnesses-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,126 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHarnessKey(t *testing.T) {
+	tests := []struct {
+		harness Harness
+		want    string
+	}{
+		{Harness{Name: "Fuzz_Nosy_Sum__", Package: "example.com/fake/parse"}, "Fuzz_Nosy_Sum__-"},
+		{Harness{Name: "Fuzz_Nosy_Sum__", Package: "example.com/fake/other"}, "Fuzz_Nosy_Sum__-"},
+		// listed in fuzzable.txt, without a package
+		{Harness{Name: "Fuzz_Nosy_Sum__", Directory: "/go/src/example.com/fake/parse"}, "Fuzz_Nosy_Sum__-"},
+	}
+	keys := map[string]bool{}
+	for _, tt := range tests {
+		key := tt.harness.Key()
+		if !strings.HasPrefix(key, tt.want) || len(key) != len(tt.want)+8 {
+			t.Errorf("Key() of %+v = %q, want %q and 8 hex digits", tt.harness, key, tt.want)
+		}
+		if key != tt.harness.Key() {
+			t.Errorf("Key() of %+v changes between calls", tt.harness)
+		}
+		if keys[key] {
+			t.Errorf("Key() of %+v = %q is not unique", tt.harness, key)
+		}
+		keys[key] = true
+	}
+	// the key does not depend on where the package is checked out
+	a := Harness{Name: "Fuzz_Nosy_Sum__", Package: "example.com/fake/parse", Directory: "/go/src/example.com/fake/parse"}
+	b := Harness{Name: "Fuzz_Nosy_Sum__", Package: "example.com/fake/parse", Directory: "/tmp/nosy/go/src/example.com/fake/parse"}
+	if a.Key() != b.Key() {
+		t.Errorf("Key() = %q and %q for the same package", a.Key(), b.Key())
+	}
+}
+
+func TestHarnessManifestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), HarnessManifestFile)
+	m := &HarnessManifest{
+		Version:     HarnessManifestVersion,
+		Target:      "fake",
+		GeneratedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Harnesses: []Harness{{
+			Name:        "Fuzz_Nosy_Point_Div__",
+			Package:     "example.com/fake/parse",
+			Directory:   "/go/src/example.com/fake/parse",
+			File:        "/go/src/example.com/fake/parse/Fuzz_Nosy_test.go",
+			Function:    "Div",
+			Receiver:    "Point",
+			Signature:   "func (p *Point) Div(d int) int",
+			Constructor: "NewPoint",
+			Support:     NativeSupport,
+		}},
+	}
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadHarnessManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, m) {
+		t.Errorf("loaded %+v, want %+v", loaded, m)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("manifest directory holds %d files, want only %s", len(entries), HarnessManifestFile)
+	}
+}
+
+func TestLoadHarnessManifestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		err      string
+	}{
+		{"not json", `{`, "unexpected end of JSON input"},
+		{"no version", `{"harnesses": []}`, "unsupported harness manifest version 0"},
+		{"newer version", `{"version": 2}`, "unsupported harness manifest version 2"},
+		{"no name", `{"version": 1, "harnesses": [{"directory": "/go/src/example.com/fake/parse"}]}`, "harness 0 has no name or directory"},
+		{"no directory", `{"version": 1, "harnesses": [{"name": "Fuzz_Nosy_Sum__"}]}`, "harness 0 has no name or directory"},
+		{
+			name: "listed twice",
+			manifest: `{"version": 1, "harnesses": [
+				{"name": "Fuzz_Nosy_Sum__", "package": "example.com/fake/parse", "directory": "/go/src/example.com/fake/parse"},
+				{"name": "Fuzz_Nosy_Sum__", "package": "example.com/fake/parse", "directory": "/go/src/example.com/fake/parse/v2"}
+			]}`,
+			err: "harness Fuzz_Nosy_Sum__ of example.com/fake/parse is listed twice",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), HarnessManifestFile)
+			if err := os.WriteFile(path, []byte(tt.manifest), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadHarnessManifest(path)
+			if err == nil || !strings.Contains(err.Error(), tt.err) || !strings.HasPrefix(err.Error(), path+": ") {
+				t.Errorf("LoadHarnessManifest() = %v, want %s: ...%s", err, path, tt.err)
+			}
+		})
+	}
+
+	// the same name in two packages is two harnesses
+	path := filepath.Join(t.TempDir(), HarnessManifestFile)
+	manifest := `{"version": 1, "harnesses": [
+		{"name": "Fuzz_Nosy_Sum__", "package": "example.com/fake/parse", "directory": "/go/src/example.com/fake/parse"},
+		{"name": "Fuzz_Nosy_Sum__", "package": "example.com/fake/other", "directory": "/go/src/example.com/fake/other"}
+	]}`
+	if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := LoadHarnessManifest(path); err != nil || len(m.Harnesses) != 2 {
+		t.Errorf("LoadHarnessManifest() = %+v, %v, want both harnesses", m, err)
+	}
+}
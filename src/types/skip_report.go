@@ -0,0 +1,140 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// version of the skip report format, parse-package writes the same format
+// from its own copy of these types
+const SkipReportVersion = 1
+
+// name of the skip report in the target's repo root
+const SkipReportFile = "nosy_skip_report.json"
+
+// why a discovered function did or did not get a harness
+const (
+	// a harness was generated for the function
+	ReasonHarness = "harness"
+	// the function's package matches ignore_packages
+	ReasonIgnoredPackage = "ignored_package"
+	// include_packages is set and does not match the function's package
+	ReasonNotIncludedPackage = "not_included_package"
+	// the function matches ignore_functions
+	ReasonIgnoredFunction = "ignored_function"
+	// include_functions is set and does not match the function
+	ReasonNotIncludedFunction = "not_included_function"
+	// the receiver or a parameter uses a type in ignore_types
+	ReasonIgnoredType = "ignored_type"
+	// the receiver or a parameter uses a type of a package in
+	// substitute_packages, which the substitute cannot stand in for
+	ReasonSubstitutedType = "substituted_type"
+	// the function is a method declared by an interface
+	ReasonInterfaceMethod = "interface_method"
+	// the function builds the receivers of methods and is not wrapped itself
+	ReasonConstructor = "constructor"
+	// the function has no receiver and no parameters
+	ReasonNoInputs = "no_inputs"
+	// a parameter is a func the fuzzer cannot produce
+	ReasonFuncParam = "func_param"
+	// a parameter is a channel the fuzzer cannot produce
+	ReasonChanParam = "chan_param"
+	// a parameter is an interface fill has no implementation for
+	ReasonInterfaceParam = "interface_param"
+	// the receiver is neither a named type nor a pointer to one
+	ReasonUnknownReceiver = "unknown_receiver"
+	// planning the wrapper failed in another way, see the detail
+	ReasonError = "error"
+)
+
+// ReportedFunction is one function found in the target and what the
+// generator did with it
+type ReportedFunction struct {
+	// Package is the import path of the function's package
+	Package  string `json:"package"`
+	Function string `json:"function"`
+	// Receiver is the receiver type of a method
+	Receiver  string `json:"receiver,omitempty"`
+	Signature string `json:"signature"`
+	// Harness is the wrapper generated for the function, empty if skipped
+	Harness string `json:"harness,omitempty"`
+	Reason  string `json:"reason"`
+	// Detail is the offending parameter type, config entry or error of a
+	// skip
+	Detail string `json:"detail,omitempty"`
+}
+
+// Name returns the function as Receiver.Function for methods
+func (f ReportedFunction) Name() string {
+	if f.Receiver != "" {
+		return f.Receiver + "." + f.Function
+	}
+	return f.Function
+}
+
+// SkipReport lists every function of one generation run and the reason each
+// did or did not get a harness
+type SkipReport struct {
+	Version     int                `json:"version"`
+	Target      string             `json:"target"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Totals      map[string]int     `json:"totals"`
+	Functions   []ReportedFunction `json:"functions"`
+}
+
+// LoadSkipReport reads the skip report at path
+func LoadSkipReport(path string) (*SkipReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &SkipReport{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if r.Version != SkipReportVersion {
+		return nil, fmt.Errorf("%s: unsupported skip report version %d", path, r.Version)
+	}
+	return r, nil
+}
+
+// Reasons returns the reasons of the report, most common first
+func (r *SkipReport) Reasons() []string {
+	var reasons []string
+	for reason := range r.Totals {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if r.Totals[reasons[i]] != r.Totals[reasons[j]] {
+			return r.Totals[reasons[i]] > r.Totals[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	return reasons
+}
+
+// WriteTotals writes the number of functions per reason
+func (r *SkipReport) WriteTotals(w io.Writer) {
+	fmt.Fprintf(w, "%d functions found, %d got a harness\n", len(r.Functions), r.Totals[ReasonHarness])
+	for _, reason := range r.Reasons() {
+		fmt.Fprintf(w, "\t%-22s %6d\n", reason, r.Totals[reason])
+	}
+}
+
+// WriteTable writes the totals followed by every function with its harness
+// or the reason it has none
+func (r *SkipReport) WriteTable(w io.Writer) {
+	r.WriteTotals(w)
+	fmt.Fprintf(w, "\n%-60s %-40s %-22s %s\n", "Package", "Function", "Reason", "Harness / Detail")
+	for _, f := range r.Functions {
+		detail := f.Detail
+		if f.Reason == ReasonHarness {
+			detail = f.Harness
+		}
+		fmt.Fprintf(w, "%-60s %-40s %-22s %s\n", f.Package, f.Name(), f.Reason, detail)
+	}
+}
@@ -0,0 +1,122 @@
+package types
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReportedFunctionName(t *testing.T) {
+	tests := []struct {
+		f    ReportedFunction
+		want string
+	}{
+		{ReportedFunction{Function: "Sum"}, "Sum"},
+		{ReportedFunction{Function: "Div", Receiver: "Point"}, "Point.Div"},
+	}
+	for _, tt := range tests {
+		if got := tt.f.Name(); got != tt.want {
+			t.Errorf("Name() of %+v = %q, want %q", tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestReasons(t *testing.T) {
+	tests := []struct {
+		name   string
+		totals map[string]int
+		want   []string
+	}{
+		{"none", nil, nil},
+		{
+			name:   "most common first",
+			totals: map[string]int{ReasonFuncParam: 2, ReasonHarness: 10, ReasonConstructor: 4},
+			want:   []string{ReasonHarness, ReasonConstructor, ReasonFuncParam},
+		},
+		{
+			name:   "ties by name",
+			totals: map[string]int{ReasonNoInputs: 3, ReasonChanParam: 3, ReasonHarness: 5, ReasonIgnoredType: 3},
+			want:   []string{ReasonHarness, ReasonChanParam, ReasonIgnoredType, ReasonNoInputs},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &SkipReport{Totals: tt.totals}
+			if got := r.Reasons(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Reasons() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	r := &SkipReport{
+		Totals: map[string]int{ReasonHarness: 1, ReasonFuncParam: 1},
+		Functions: []ReportedFunction{
+			{Package: "example.com/fake/parse", Function: "Div", Receiver: "Point", Harness: "Fuzz_Nosy_Point_Div__", Reason: ReasonHarness},
+			{Package: "example.com/fake/parse", Function: "Each", Reason: ReasonFuncParam, Detail: "func(int) error"},
+		},
+	}
+	var out bytes.Buffer
+	r.WriteTable(&out)
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	want := [][]string{
+		{"2 functions found, 1 got a harness"},
+		{ReasonFuncParam, "1"},
+		{ReasonHarness, "1"},
+		nil,
+		{"Package", "Function", "Reason", "Harness / Detail"},
+		{"example.com/fake/parse", "Point.Div", ReasonHarness, "Fuzz_Nosy_Point_Div__"},
+		{"example.com/fake/parse", "Each", ReasonFuncParam, "func(int) error"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("WriteTable() wrote %d lines, want %d:\n%s", len(lines), len(want), out.String())
+	}
+	for i, fields := range want {
+		for _, field := range fields {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("line %d %q lacks %q", i, lines[i], field)
+			}
+		}
+	}
+}
+
+func TestLoadSkipReport(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+		err    string
+	}{
+		{
+			name:   "valid",
+			report: `{"version": 1, "target": "fake", "totals": {"harness": 1}, "functions": [{"package": "example.com/fake/parse", "function": "Sum", "harness": "Fuzz_Nosy_Sum__", "reason": "harness"}]}`,
+		},
+		{name: "not json", report: `{`, err: "unexpected end of JSON input"},
+		{name: "no version", report: `{"target": "fake"}`, err: "unsupported skip report version 0"},
+		{name: "newer version", report: `{"version": 2}`, err: "unsupported skip report version 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), SkipReportFile)
+			if err := os.WriteFile(path, []byte(tt.report), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			r, err := LoadSkipReport(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) || !strings.HasPrefix(err.Error(), path+": ") {
+					t.Errorf("LoadSkipReport() = %v, want %s: ...%s", err, path, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Target != "fake" || r.Totals[ReasonHarness] != 1 || len(r.Functions) != 1 || r.Functions[0].Harness != "Fuzz_Nosy_Sum__" {
+				t.Errorf("LoadSkipReport() = %+v", r)
+			}
+		})
+	}
+}
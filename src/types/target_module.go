@@ -0,0 +1,159 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// name of the file nosy init saves the target's module in, in the target
+// directory
+const TargetModuleFile = "target_module.json"
+
+// TargetModule is what nosy init found out about the target from its go.mod,
+// or its go.work when the repository root is a workspace
+type TargetModule struct {
+	// ImportPrefix is the import path of the repository root
+	ImportPrefix string `json:"import_prefix"`
+	// ModulePath is the module declared by the root go.mod, or the import
+	// prefix of a workspace
+	ModulePath string `json:"module_path"`
+	// GoVersion is the go directive of the go.mod or go.work
+	GoVersion string `json:"go_version,omitempty"`
+	// Modules are the paths of the workspace's modules
+	Modules []string `json:"modules,omitempty"`
+	// Commit is the commit checked out, suffixed with -dirty when the local
+	// working tree had uncommitted changes, empty for a tree outside git
+	Commit string `json:"commit,omitempty"`
+}
+
+// InferTargetModule reads the go.mod at the root of repo_dir or, without
+// one, its go.work
+func InferTargetModule(repo_dir string) (*TargetModule, error) {
+	mod_path := filepath.Join(repo_dir, "go.mod")
+	data, err := os.ReadFile(mod_path)
+	if err == nil {
+		f, err := modfile.ParseLax(mod_path, data, nil)
+		if err != nil {
+			return nil, err
+		}
+		if f.Module == nil || f.Module.Mod.Path == "" {
+			return nil, fmt.Errorf("%s declares no module", mod_path)
+		}
+		m := &TargetModule{ImportPrefix: f.Module.Mod.Path, ModulePath: f.Module.Mod.Path}
+		if f.Go != nil {
+			m.GoVersion = f.Go.Version
+		}
+		return m, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	work_path := filepath.Join(repo_dir, "go.work")
+	data, err = os.ReadFile(work_path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s has neither a go.mod nor a go.work", repo_dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	w, err := modfile.ParseWork(work_path, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	m := &TargetModule{}
+	if w.Go != nil {
+		m.GoVersion = w.Go.Version
+	}
+	// the import path of the root is a module's path without the directory
+	// it is used from
+	for _, use := range w.Use {
+		dir := path.Clean(filepath.ToSlash(use.Path))
+		used, err := InferTargetModule(filepath.Join(repo_dir, filepath.FromSlash(dir)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: use %s: %w", work_path, use.Path, err)
+		}
+		m.Modules = append(m.Modules, used.ModulePath)
+		switch {
+		case m.ImportPrefix != "":
+		case dir == ".":
+			m.ImportPrefix = used.ModulePath
+		case strings.HasSuffix(used.ModulePath, "/"+dir):
+			m.ImportPrefix = strings.TrimSuffix(used.ModulePath, "/"+dir)
+		}
+	}
+	if m.ImportPrefix == "" {
+		return nil, fmt.Errorf("%s: cannot tell the import path of the repository root from the paths of its modules, set target_repo_import_prefix", work_path)
+	}
+	m.ModulePath = m.ImportPrefix
+	return m, nil
+}
+
+// LoadTargetModule reads the module saved by nosy init
+func LoadTargetModule(path string) (*TargetModule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &TargetModule{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Save writes the module to path
+func (m *TargetModule) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Resolve fills the import prefix and module declaration the config leaves
+// out from the module and returns a warning for each the config sets to a
+// different value. The module declaration of the go.mod always wins as
+// harnesses are placed by it.
+func (m *TargetModule) Resolve(c *TargetRepoConfig) []ConfigIssue {
+	var warnings []ConfigIssue
+	if c.TargetRepoImportPrefix == "" {
+		c.TargetRepoImportPrefix = m.ImportPrefix
+	} else if c.TargetRepoImportPrefix != m.ImportPrefix {
+		warnings = append(warnings, ConfigIssue{"target_repo_import_prefix",
+			fmt.Sprintf("%q differs from %q found in the target's go.mod, keeping %q", c.TargetRepoImportPrefix, m.ImportPrefix, c.TargetRepoImportPrefix)})
+	}
+	if c.TargetModuleDeclaration != "" && c.TargetModuleDeclaration != m.ModulePath {
+		warnings = append(warnings, ConfigIssue{"target_mod_self_declaration",
+			fmt.Sprintf("%q differs from module %q in the target's go.mod, using %q", c.TargetModuleDeclaration, m.ModulePath, m.ModulePath)})
+	}
+	c.TargetModuleDeclaration = m.ModulePath
+	if old := GoVersionOlder(c.TargetGoVersion, m.GoVersion); old {
+		warnings = append(warnings, ConfigIssue{"go_version",
+			fmt.Sprintf("%s is older than go %s required by the target", c.TargetGoVersion, m.GoVersion)})
+	}
+	return warnings
+}
+
+// GoVersionOlder reports if the go command named go_version, e.g. go1.21.5,
+// is older than the go directive required. A plain "go" is never older.
+func GoVersionOlder(go_version string, required string) bool {
+	version := strings.TrimPrefix(go_version, "go")
+	if version == "" || required == "" || version == go_version {
+		return false
+	}
+	have, want := "v"+version, "v"+required
+	if !semver.IsValid(have) || !semver.IsValid(want) {
+		return false
+	}
+	return semver.Compare(have, want) < 0
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInferTargetModule(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  *TargetModule
+		err   bool
+	}{
+		{
+			name:  "go.mod",
+			files: map[string]string{"go.mod": "module example.com/fake\n\ngo 1.21\n"},
+			want:  &TargetModule{ImportPrefix: "example.com/fake", ModulePath: "example.com/fake", GoVersion: "1.21"},
+		},
+		{
+			name:  "go.mod without go directive",
+			files: map[string]string{"go.mod": "module example.com/fake\n"},
+			want:  &TargetModule{ImportPrefix: "example.com/fake", ModulePath: "example.com/fake"},
+		},
+		{
+			name:  "go.mod wins over go.work",
+			files: map[string]string{"go.mod": "module example.com/fake\n", "go.work": "go 1.22\n\nuse ./sub\n"},
+			want:  &TargetModule{ImportPrefix: "example.com/fake", ModulePath: "example.com/fake"},
+		},
+		{
+			name:  "go.mod declares no module",
+			files: map[string]string{"go.mod": "go 1.21\n"},
+			err:   true,
+		},
+		{
+			name: "workspace of subdirectories",
+			files: map[string]string{
+				"go.work":         "go 1.22\n\nuse (\n\t./api\n\t./cmd/tool\n)\n",
+				"api/go.mod":      "module example.com/fake/api\n",
+				"cmd/tool/go.mod": "module example.com/fake/cmd/tool\n",
+			},
+			want: &TargetModule{
+				ImportPrefix: "example.com/fake",
+				ModulePath:   "example.com/fake",
+				GoVersion:    "1.22",
+				Modules:      []string{"example.com/fake/api", "example.com/fake/cmd/tool"},
+			},
+		},
+		{
+			name: "workspace of unrelated modules",
+			files: map[string]string{
+				"go.work":    "go 1.22\n\nuse ./api\n",
+				"api/go.mod": "module example.com/other\n",
+			},
+			err: true,
+		},
+		{
+			name: "workspace using a missing module",
+			files: map[string]string{
+				"go.work": "go 1.22\n\nuse ./api\n",
+			},
+			err: true,
+		},
+		{
+			name: "neither go.mod nor go.work",
+			err:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := InferTargetModule(dir)
+			if (err != nil) != tt.err {
+				t.Fatalf("InferTargetModule() error = %v, want error %t", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InferTargetModule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoVersionOlder(t *testing.T) {
+	tests := []struct {
+		go_version string
+		required   string
+		want       bool
+	}{
+		{"go", "1.21", false},
+		{"go1.20", "1.21", true},
+		{"go1.21.5", "1.21", false},
+		{"go1.21.5", "1.22.0", true},
+		{"go1.22", "", false},
+		{"/usr/local/bin/go", "1.21", false},
+	}
+	for _, tt := range tests {
+		if got := GoVersionOlder(tt.go_version, tt.required); got != tt.want {
+			t.Errorf("GoVersionOlder(%q, %q) = %t, want %t", tt.go_version, tt.required, got, tt.want)
+		}
+	}
+}
@@ -19,7 +19,7 @@ type TargetPackage struct {
 
 // helper function for creating new TargetPackage objects
 func NewTargetPackage(name string, real_path string, target_config TargetRepoConfig) TargetPackage {
-	fuzz_dir_path_prefix := fmt.Sprintf("./fuzzing_directory/%s/go/src/", target_config)
+	fuzz_dir_path_prefix := fmt.Sprintf("./fuzzing_directory/%s/go/src/", target_config.TargetRepo)
 	trimmed_path := strings.TrimPrefix(real_path, fuzz_dir_path_prefix)
 	s := strings.Split(trimmed_path, "/")
 	trimmed_path = strings.TrimSuffix(trimmed_path, s[len(s)-1])
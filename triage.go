@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/infosecual/nosy/src/campaign"
+	"github.com/infosecual/nosy/src/triage"
+)
+
+// triage_logs parses the crashes out of fuzzing logs. When results_dir is
+// set, crashes are linked to the inputs the fuzz scripts saved there, under
+// the harness key the log is named after.
+func triage_logs(log_paths []string, results_dir string) ([]triage.Crash, error) {
+	var crashes []triage.Crash
+	for _, log_path := range log_paths {
+		found, err := triage.ParseFile(log_path)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.TrimSuffix(filepath.Base(log_path), ".log")
+		for i, c := range found {
+			if results_dir == "" || c.InputID() == "" {
+				continue
+			}
+			result := filepath.Join(results_dir, key, c.InputID())
+			if _, err := os.Stat(result); err == nil {
+				found[i].Result = result
+			}
+		}
+		crashes = append(crashes, found...)
+	}
+	return crashes, nil
+}
+
+// bucket_crashes files the crashes into buckets and returns the IDs of the
+// buckets that were not known before
+func bucket_crashes(buckets *triage.Buckets, crashes []triage.Crash) map[string]bool {
+	new_buckets := map[string]bool{}
+	for i := range crashes {
+		bucket, is_new := buckets.Add(&crashes[i])
+		if is_new {
+			new_buckets[bucket.ID] = true
+		}
+	}
+	return new_buckets
+}
+
+// summarize_crashes buckets the crashes in the output of a harness run
+// against the saved buckets, which are left untouched, and counts them by
+// bucket for the campaign state
+func summarize_crashes(buckets_path string, output []byte) ([]campaign.CrashSummary, error) {
+	crashes, err := triage.Parse(bytes.NewReader(output))
+	if err != nil || len(crashes) == 0 {
+		return nil, err
+	}
+	buckets, err := triage.LoadBuckets(buckets_path, triage.DefaultFrames)
+	if err != nil {
+		return nil, err
+	}
+	new_buckets := bucket_crashes(buckets, crashes)
+	var summaries []campaign.CrashSummary
+	index := map[string]int{}
+	for _, c := range crashes {
+		i, ok := index[c.Bucket]
+		if !ok {
+			i = len(summaries)
+			index[c.Bucket] = i
+			summaries = append(summaries, campaign.CrashSummary{
+				Bucket: c.Bucket,
+				Panic:  buckets.Buckets[c.Bucket].Panic,
+				New:    new_buckets[c.Bucket],
+			})
+		}
+		summaries[i].Count++
+	}
+	return summaries, nil
+}
+
+// print_buckets prints a short table of the buckets hit by crashes, new
+// buckets first, to the terminal
+func print_buckets(buckets *triage.Buckets, crashes []triage.Crash, new_buckets map[string]bool) {
+	hit := map[string]bool{}
+	for _, c := range crashes {
+		hit[c.Bucket] = true
+	}
+	fmt.Printf("\nFound %d crashes in %d buckets, %d new\n", len(crashes), len(hit), len(new_buckets))
+	for _, is_new := range []bool{true, false} {
+		for _, bucket := range buckets.Sorted() {
+			if !hit[bucket.ID] || new_buckets[bucket.ID] != is_new {
+				continue
+			}
+			label := "known"
+			if is_new {
+				label = "NEW"
+			}
+			fmt.Printf("\t%s\t%s\t%d duplicates\t%s\t%s\n", label, bucket.ID, bucket.Duplicates(),
+				strings.Join(bucket.Harnesses, ","), bucket.Panic)
+		}
+	}
+}
+
+// triage_target triages every fuzzing log of the target and writes the
+// reports to the target's triage directory. Crashes are bucketed by stack
+// hash against the buckets of earlier runs, and one input per bucket is kept
+// under triage/buckets.
+func triage_target(target_dir string) error {
+	log_paths, err := filepath.Glob(filepath.Join(target_dir, "logs", "*.log"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(log_paths)
+	crashes, err := triage_logs(log_paths, filepath.Join(target_dir, "results"))
+	if err != nil {
+		return err
+	}
+
+	triage_dir := filepath.Join(target_dir, "triage")
+	if err := os.MkdirAll(triage_dir, 0o755); err != nil {
+		return err
+	}
+	buckets_path := filepath.Join(triage_dir, "buckets.json")
+	buckets, err := triage.LoadBuckets(buckets_path, triage.DefaultFrames)
+	if err != nil {
+		return err
+	}
+	new_buckets := bucket_crashes(buckets, crashes)
+	for _, bucket := range buckets.Buckets {
+		if err := bucket.KeepRepresentative(filepath.Join(triage_dir, "buckets")); err != nil {
+			fmt.Printf("could not keep the input of bucket %s: %s\n", bucket.ID, err)
+		}
+	}
+	if err := buckets.Save(buckets_path); err != nil {
+		return err
+	}
+
+	csv_path := filepath.Join(triage_dir, "crashes.csv")
+	json_path := filepath.Join(triage_dir, "crashes.json")
+	if err := triage.WriteReports(crashes, csv_path, json_path); err != nil {
+		return err
+	}
+	print_buckets(buckets, crashes, new_buckets)
+	fmt.Println("Triage reports written to", strings.TrimSuffix(csv_path, ".csv")+".{csv,json}")
+	fmt.Println("Crash buckets written to", buckets_path)
+	return nil
+}
+
+// triage_files is the "nosy triage" command, it triages the given logs and
+// writes the reports to the requested files, or CSV to stdout if none.
+// Buckets are only kept across runs when buckets_path is set.
+func triage_files(log_paths []string, csv_path string, json_path string, buckets_path string, frames int) error {
+	crashes, err := triage_logs(log_paths, "")
+	if err != nil {
+		return err
+	}
+	buckets := triage.NewBuckets(frames)
+	if buckets_path != "" {
+		if buckets, err = triage.LoadBuckets(buckets_path, frames); err != nil {
+			return err
+		}
+	}
+	new_buckets := bucket_crashes(buckets, crashes)
+	if buckets_path != "" {
+		if err := buckets.Save(buckets_path); err != nil {
+			return err
+		}
+	}
+
+	if csv_path == "" && json_path == "" {
+		return triage.WriteCSV(os.Stdout, crashes)
+	}
+	if err := triage.WriteReports(crashes, csv_path, json_path); err != nil {
+		return err
+	}
+	print_buckets(buckets, crashes, new_buckets)
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// generate_update_script writes the script that moves the initialized target
+// to the latest commit of its branch, or to target_repo_commit, in place. A
+// local working tree without a pinned commit is copied again instead.
+func generate_update_script(target_dir string) error {
+	script := fmt.Sprintf("REPO_URL=\"%s\"\n", TargetConfig.TargetRepoURL)
+	script += fmt.Sprintf("REPO_PREFIX=\"%s\"\n", TargetConfig.TargetRepoImportPrefix)
+	script += fmt.Sprintf("BRANCH=\"%s\"\n", TargetConfig.TargetRepoBranch)
+	script += fmt.Sprintf("COMMIT=\"%s\"\n", TargetConfig.TargetRepoCommit)
+	if TargetConfig.TargetLocalPath != "" {
+		script += fmt.Sprintf("LOCAL=\"%s\"\n", local_target_mount)
+	}
+	script += `set -e
+git_() { git -c safe.directory='*' -c advice.detachedHead=false "$@"; }
+rm -f /staging/target_commit
+cd /go/src/$REPO_PREFIX
+if [ -n "$LOCAL" ] && [ -z "$COMMIT" ]; then
+	find . -mindepth 1 -maxdepth 1 -exec rm -rf {} +
+	cp -a $LOCAL/. .
+else
+	# drop the harnesses and go.mod changes of the last generation
+	git_ reset --quiet --hard
+	git_ clean --quiet -fd
+	# fetch from where the config says the target is now, a tree copied
+	# from a local repository has that repository's remotes
+	if [ -z "$LOCAL" ]; then
+		git_ remote set-url origin $REPO_URL 2> /dev/null || git_ remote add origin $REPO_URL
+	fi
+	SOURCE=${LOCAL:-origin}
+	git_ fetch --quiet --tags --force $SOURCE '+refs/heads/*:refs/remotes/origin/*'
+	if [ -n "$COMMIT" ]; then
+		if git_ rev-parse -q --verify "$COMMIT^{commit}" > /dev/null; then
+			git_ checkout --detach "$COMMIT"
+		else
+			git_ fetch $SOURCE "$COMMIT"
+			git_ checkout --detach FETCH_HEAD
+		fi
+	else
+		if [ -z "$BRANCH" ]; then
+			BRANCH=$(git_ symbolic-ref --short refs/remotes/origin/HEAD)
+			BRANCH=${BRANCH#origin/}
+		fi
+		git_ checkout --quiet -B "$BRANCH" "origin/$BRANCH"
+	fi
+fi
+if git_ rev-parse --verify -q HEAD > /staging/target_commit; then
+	if [ -n "$(git_ status --porcelain)" ]; then
+		echo "$(cat /staging/target_commit)-dirty" > /staging/target_commit
+	fi
+else
+	rm -f /staging/target_commit
+fi
+mkdir -p nosy_fuzz_dir
+go get -t -d ./...
+if [ "$NOSY_RUNNER" != "local" ]; then
+	groupadd user
+	useradd -s /bin/bash -d / -m -g user user
+	chown user -R /staging
+fi
+chmod -R u+w /staging
+`
+	return os.WriteFile(filepath.Join(target_dir, "update_target.sh"), []byte(script), 0o755)
+}
+
+// update_target moves an initialized target to a newer commit and
+// regenerates its harnesses. Unlike init it keeps the cache, results, logs,
+// triage and campaign of the target, later crashes record the new commit.
+func update_target(ctx context.Context) error {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
+	local_repo_path := fmt.Sprintf("%s/go/src/%s", target_dir, TargetConfig.TargetRepoImportPrefix)
+	if _, err := os.Stat(local_repo_path); err != nil {
+		return fmt.Errorf("target %s has not been initialized, run \"nosy init\" first", TargetConfig.TargetRepo)
+	}
+
+	fmt.Println("Updating target repo...")
+	fmt.Println("\tName: ", TargetConfig.TargetRepo)
+	if TargetCommit != "" {
+		fmt.Println("\tFrom commit: ", TargetCommit)
+	}
+	if err := generate_update_script(target_dir); err != nil {
+		return err
+	}
+	mounts, err := clone_mounts(target_dir)
+	if err != nil {
+		return err
+	}
+	spec := RunSpec{
+		Name:   "update_target.sh",
+		Script: "/staging/update_target.sh",
+		Mounts: append(mounts, Mount{HostPath: target_dir + "/go", ContainerPath: "/go"}),
+	}
+	if err := record_step(spec.Name, ActiveRunner.Run(ctx, spec)); err != nil {
+		return fmt.Errorf("%s: %w", spec.Name, err)
+	}
+
+	// the target stays where init put it, a new module path needs a new init
+	prefix := TargetConfig.TargetRepoImportPrefix
+	if err := infer_target_module(target_dir, local_repo_path); err != nil {
+		return record_step("read target go.mod", err)
+	}
+	if TargetConfig.TargetRepoImportPrefix != prefix {
+		return record_step("read target go.mod", fmt.Errorf("the import prefix changed from %s to %s, run \"nosy init\" to start over", prefix, TargetConfig.TargetRepoImportPrefix))
+	}
+
+	if TargetConfig.OfflineModules {
+		if err := fetch_modules(ctx, target_dir); err != nil {
+			return err
+		}
+		if err := use_module_proxy(); err != nil {
+			return err
+		}
+	}
+
+	if err := generate_fuzz_harnesses(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("\nRun \"nosy fuzz %s\" to fuzz the updated target, the corpora of earlier runs are kept\n", ConfigPath)
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	nt "github.com/infosecual/nosy/src/types"
+)
+
+func TestGenerateUpdateScript(t *testing.T) {
+	defer func(c nt.TargetRepoConfig) { TargetConfig = c }(TargetConfig)
+	tests := []struct {
+		name   string
+		config nt.TargetRepoConfig
+		want   []string
+		local  bool
+	}{
+		{
+			name:   "branch",
+			config: nt.TargetRepoConfig{TargetRepoURL: "https://example.com/fake.git", TargetRepoImportPrefix: "example.com/fake", TargetRepoBranch: "main"},
+			want:   []string{`REPO_URL="https://example.com/fake.git"`, `REPO_PREFIX="example.com/fake"`, `BRANCH="main"`, `COMMIT=""`},
+		},
+		{
+			name:   "pinned commit",
+			config: nt.TargetRepoConfig{TargetRepoURL: "https://example.com/fake.git", TargetRepoImportPrefix: "example.com/fake", TargetRepoCommit: "v1.2.0"},
+			want:   []string{`BRANCH=""`, `COMMIT="v1.2.0"`},
+		},
+		{
+			name:   "local",
+			config: nt.TargetRepoConfig{TargetLocalPath: "/home/u/fake", TargetRepoImportPrefix: "example.com/fake"},
+			want:   []string{`REPO_URL=""`, `LOCAL="` + local_target_mount + `"`},
+			local:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			TargetConfig = tt.config
+			dir := t.TempDir()
+			if err := generate_update_script(dir); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "update_target.sh")
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(string(data), "\n")
+			for _, want := range tt.want {
+				found := false
+				for _, line := range lines {
+					found = found || line == want
+				}
+				if !found {
+					t.Errorf("script lacks the line %s:\n%s", want, data)
+				}
+			}
+			if strings.Contains(string(data), "LOCAL=\"") != tt.local {
+				t.Errorf("script sets LOCAL %t, want %t", !tt.local, tt.local)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm()&0o100 == 0 {
+				t.Errorf("script is not executable: %v", err)
+			}
+		})
+	}
+}
+
+// git runs git in dir and returns its trimmed output
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=nosy", "-c", "user.email=nosy@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// the update script run by the local runner moves an initialized target to
+// the commit the config asks for
+func TestUpdateScript(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	defer func(c nt.TargetRepoConfig) { TargetConfig = c }(TargetConfig)
+
+	origin := t.TempDir()
+	write_files(t, origin, map[string]string{"go.mod": "module example.com/fake\n\ngo 1.21\n", "fake.go": "package fake\n"})
+	git(t, origin, "init", "-q")
+	git(t, origin, "add", "-A")
+	git(t, origin, "commit", "-qm", "first")
+	first := git(t, origin, "rev-parse", "HEAD")
+	write_files(t, origin, map[string]string{"fake.go": "package fake\n\nfunc Sum(a, b int) int { return a + b }\n"})
+	git(t, origin, "commit", "-qam", "second")
+	second := git(t, origin, "rev-parse", "HEAD")
+
+	tests := []struct {
+		name   string
+		config nt.TargetRepoConfig
+		// local has a change not committed yet
+		local  bool
+		commit string
+	}{
+		{"branch", nt.TargetRepoConfig{TargetRepoURL: origin, TargetRepoBranch: "main"}, false, second},
+		{"default branch", nt.TargetRepoConfig{TargetRepoURL: origin}, false, second},
+		{"pinned commit", nt.TargetRepoConfig{TargetRepoURL: origin, TargetRepoBranch: "main", TargetRepoCommit: first}, false, first},
+		{"local working tree", nt.TargetRepoConfig{TargetLocalPath: origin}, true, second + "-dirty"},
+		{"local pinned commit", nt.TargetRepoConfig{TargetLocalPath: origin, TargetRepoCommit: first}, true, first},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			TargetConfig = tt.config
+			TargetConfig.TargetRepoImportPrefix = "example.com/fake"
+			if tt.local {
+				write_files(t, origin, map[string]string{"wip.go": "package fake\n"})
+				defer os.Remove(filepath.Join(origin, "wip.go"))
+			}
+
+			// a target initialized at the first commit, with the harnesses
+			// of its last generation
+			target_dir := t.TempDir()
+			repo := filepath.Join(target_dir, "go", "src", "example.com", "fake")
+			git(t, target_dir, "clone", "-q", origin, repo)
+			git(t, repo, "checkout", "-q", "--detach", first)
+			git(t, repo, "remote", "set-url", "origin", "/nonexistent")
+			write_files(t, repo, map[string]string{"Fuzz_Nosy_test.go": "package fake\n", "go.mod": "module example.com/fake\n\ngo 1.21\n\nrequire example.com/fill v1.0.0\n"})
+
+			if err := generate_update_script(target_dir); err != nil {
+				t.Fatal(err)
+			}
+			mounts, err := clone_mounts(target_dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			err = (&local_runner{}).Run(context.Background(), RunSpec{
+				Name:   "update_target.sh",
+				Script: "/staging/update_target.sh",
+				Mounts: append(mounts, Mount{HostPath: target_dir + "/go", ContainerPath: "/go"}),
+				Env:    []string{"GOFLAGS=", "GOPROXY=off"},
+				Stdout: &out,
+				Stderr: &out,
+			})
+			if err != nil {
+				t.Fatalf("update_target.sh: %v\n%s", err, out.String())
+			}
+
+			data, err := os.ReadFile(filepath.Join(target_dir, "target_commit"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(data)); got != tt.commit {
+				t.Errorf("target_commit %s, want %s", got, tt.commit)
+			}
+			if _, err := os.Stat(filepath.Join(repo, "Fuzz_Nosy_test.go")); err == nil {
+				t.Error("the harnesses of the last generation were kept")
+			}
+			if _, err := os.Stat(filepath.Join(repo, "wip.go")); (err == nil) != (tt.local && tt.commit != first) {
+				t.Errorf("uncommitted change copied %t", err == nil)
+			}
+			if _, err := os.Stat(filepath.Join(repo, "nosy_fuzz_dir")); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
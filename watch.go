@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/infosecual/nosy/src/campaign"
+)
+
+// ANSI sequences to clear the terminal and move the cursor home
+const clear_screen = "\033[H\033[2J"
+
+// watch_status redraws the campaign dashboard every interval until ctx is
+// cancelled
+func watch_status(ctx context.Context, interval time.Duration) error {
+	// get pwd for subsequent commands
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	target_dir := fmt.Sprintf("%s/fuzzing_directory/%s", pwd, TargetConfig.TargetRepo)
+
+	// the manifest only changes on generate, it is read once
+	harnesses := 0
+	local_repo_path := fmt.Sprintf("%s/go/src/%s", target_dir, TargetConfig.TargetRepoImportPrefix)
+	if err := load_harnesses(local_repo_path, target_dir+"/go"); err == nil {
+		harnesses = len(Harnesses)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// draw off screen first so the terminal does not flicker
+		var frame bytes.Buffer
+		if err := render_dashboard(&frame, target_dir, harnesses, interval, time.Now()); err != nil {
+			return err
+		}
+		fmt.Print(clear_screen)
+		os.Stdout.Write(frame.Bytes())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// harness_slice returns how long a run of h is meant to take
+func harness_slice(s *campaign.State, h *campaign.Harness) time.Duration {
+	if s.Budget != nil && h.SliceSeconds > 0 {
+		return time.Duration(h.SliceSeconds) * time.Second
+	}
+	return time.Duration(s.SecondsPerHarness) * time.Second
+}
+
+// estimate_remaining guesses how long the campaign still runs from the time
+// left of the running harnesses and the slices not handed out yet, spread
+// over the campaign's jobs. Build time is not accounted for.
+func estimate_remaining(s *campaign.State, now time.Time) time.Duration {
+	jobs := s.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	var running, queued time.Duration
+	for _, h := range s.Harnesses {
+		switch {
+		case h.Status == campaign.Running && h.StartedAt != nil:
+			left := harness_slice(s, h) - now.Sub(*h.StartedAt)
+			if left > running {
+				running = left
+			}
+		case h.Status == campaign.Pending && s.Budget == nil:
+			queued += harness_slice(s, h)
+		}
+	}
+	if s.Budget != nil {
+		queued = time.Duration(s.Budget.Remaining()) * time.Second
+	}
+	return running + queued/time.Duration(jobs)
+}
+
+// render_dashboard draws one frame of nosy status --watch from the campaign
+// state and the number of harnesses in the manifest
+func render_dashboard(w io.Writer, target_dir string, harnesses int, interval time.Duration, now time.Time) error {
+	fmt.Fprintf(w, "nosy: %s  %s  (every %s, Ctrl-C to quit)\n\n", TargetConfig.TargetRepo, now.Format("15:04:05"), interval)
+
+	state, err := campaign.Load(filepath.Join(target_dir, campaign.StateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(w, "No campaign yet, start one with \"nosy fuzz\"")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	type running_harness struct {
+		*campaign.Harness
+		slice time.Duration
+	}
+	var running []running_harness
+	finished := 0
+	counts := map[campaign.Status]int{}
+	var rate float64
+	var eta time.Duration
+	state.View(func(s *campaign.State) {
+		for _, h := range s.Harnesses {
+			counts[h.Status]++
+			if h.Status.Finished() {
+				finished++
+			}
+			if h.Status == campaign.Running {
+				running = append(running, running_harness{h, harness_slice(s, h)})
+				if h.Stats != nil {
+					rate += h.Stats.ExecsPerSec
+				}
+			}
+		}
+		eta = estimate_remaining(s, now)
+	})
+
+	width := 40
+	done := 0
+	if harnesses > 0 {
+		done = width * finished / harnesses
+		if done > width {
+			done = width
+		}
+	}
+	fmt.Fprintf(w, "Progress:  [%s%s] %d/%d generated harnesses\n",
+		strings.Repeat("#", done), strings.Repeat(".", width-done), finished, harnesses)
+	fmt.Fprintf(w, "           %d running, %d pending, %d done, %d crashed, %d build-failed\n",
+		counts[campaign.Running], counts[campaign.Pending], counts[campaign.Done],
+		counts[campaign.Crashed], counts[campaign.BuildFailed])
+	if b := state.Budget; b != nil {
+		fmt.Fprintf(w, "Budget:    %ds of %ds handed out, round %d\n", b.SpentSeconds, b.TotalSeconds, b.Round)
+	}
+	fmt.Fprintf(w, "Exec/s:    %.0f\n", rate)
+	if counts[campaign.Running] > 0 || counts[campaign.Pending] > 0 {
+		fmt.Fprintf(w, "ETA:       ~%s\n", eta.Round(time.Second))
+	}
+
+	fmt.Fprintf(w, "\nRunning:\n")
+	if len(running) == 0 {
+		fmt.Fprintln(w, "\tnothing")
+	} else {
+		fmt.Fprintf(w, "\t%-40s %12s %12s %10s %8s %8s %8s\n", "Harness", "Elapsed", "Execs", "Exec/s", "Corpus", "New", "Workers")
+	}
+	for _, h := range running {
+		elapsed := time.Duration(0)
+		if h.StartedAt != nil {
+			elapsed = now.Sub(*h.StartedAt).Round(time.Second)
+		}
+		stats := campaign.Stats{}
+		if h.Stats != nil {
+			stats = *h.Stats
+		}
+		fmt.Fprintf(w, "\t%-40s %12s %12d %10.0f %8d %8d %8d\n", h.Key,
+			fmt.Sprintf("%s/%s", elapsed, h.slice), stats.Execs, stats.ExecsPerSec,
+			stats.Corpus, stats.NewInteresting, stats.Workers)
+	}
+	render_crashes(w, state)
+	return nil
+}
+
+// render_crashes lists the crashes of the last run of every harness by
+// bucket, as the fuzz stage recorded them in the campaign state
+func render_crashes(w io.Writer, state *campaign.State) {
+	type bucket_hits struct {
+		campaign.CrashSummary
+		harnesses []string
+	}
+	hits := map[string]*bucket_hits{}
+	total, new_buckets := 0, 0
+	state.View(func(s *campaign.State) {
+		for _, h := range s.Harnesses {
+			for _, c := range h.Crashes {
+				total += c.Count
+				b, ok := hits[c.Bucket]
+				if !ok {
+					b = &bucket_hits{CrashSummary: campaign.CrashSummary{Bucket: c.Bucket, Panic: c.Panic}}
+					hits[c.Bucket] = b
+				}
+				if c.New && !b.New {
+					b.New = true
+					new_buckets++
+				}
+				b.Count += c.Count
+				b.harnesses = append(b.harnesses, h.Key)
+			}
+		}
+	})
+
+	fmt.Fprintf(w, "\nCrashes: %d in %d buckets, %d new\n", total, len(hits), new_buckets)
+	var ids []string
+	for id := range hits {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if hits[ids[i]].Count != hits[ids[j]].Count {
+			return hits[ids[i]].Count > hits[ids[j]].Count
+		}
+		return ids[i] < ids[j]
+	})
+	for _, id := range ids {
+		bucket := hits[id]
+		label := "known"
+		if bucket.New {
+			label = "NEW"
+		}
+		fmt.Fprintf(w, "\t%-5s %s %4d  %-40s %s\n", label, id, bucket.Count,
+			strings.Join(bucket.harnesses, ","), bucket.Panic)
+	}
+}